@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/coopernurse/barrister-go"
+	"io"
+	"os"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run compares the IDL files named in args and returns the exit status:
+// 1 if a breaking change was found, 2 if the args or IDL files are invalid
+func run(args []string, out io.Writer, errOut io.Writer) int {
+	var quiet bool
+	var side string
+
+	flags := flag.NewFlagSet("barrister-compat", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	flags.BoolVar(&quiet, "q", false, "Only print breaking changes")
+	flags.StringVar(&side, "breaks", "clients", `Which breaking changes cause a non-zero exit. Valid values: "clients", "servers", "both"`)
+	if flags.Parse(args) != nil {
+		return 2
+	}
+
+	if flags.NArg() != 2 {
+		fmt.Fprintf(errOut, "Usage: barrister-compat old.json|old.idl new.json|new.idl\n")
+		flags.PrintDefaults()
+		return 2
+	}
+
+	if side != "clients" && side != "servers" && side != "both" {
+		fmt.Fprintf(errOut, `Invalid value %q for flag "breaks". Valid values: "clients", "servers", "both".`+"\n", side)
+		return 2
+	}
+
+	oldIdl, err := loadIdl(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 2
+	}
+	newIdl, err := loadIdl(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 2
+	}
+
+	breaking := false
+	for _, c := range barrister.CompareIdl(oldIdl, newIdl) {
+		fails := (c.BreaksClients && side != "servers") || (c.BreaksServers && side != "clients")
+		if fails {
+			breaking = true
+		}
+		if quiet && !c.Breaking() {
+			continue
+		}
+		fmt.Fprintf(out, "%-28s %s\n", classify(c), c)
+	}
+
+	if breaking {
+		return 1
+	}
+	return 0
+}
+
+func classify(c barrister.CompatChange) string {
+	switch {
+	case c.BreaksClients && c.BreaksServers:
+		return "BREAKING (clients, servers)"
+	case c.BreaksClients:
+		return "BREAKING (clients)"
+	case c.BreaksServers:
+		return "BREAKING (servers)"
+	}
+	return "compatible"
+}
+
+func loadIdl(filename string) (*barrister.Idl, error) {
+	idl, err := barrister.LoadIdlFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading IDL from %s: %s", filename, err)
+	}
+	return idl, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const oldIdl = `
+struct User {
+	id string
+}
+
+interface UserService {
+	get(id string) User
+}
+`
+
+func writeIdl(t *testing.T, dir string, name string, src string) string {
+	file := filepath.Join(dir, name)
+	err := os.WriteFile(file, []byte(src), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestRunExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	old := writeIdl(t, dir, "old.idl", oldIdl)
+	added := writeIdl(t, dir, "added.idl", oldIdl+`
+interface AuditService {
+	log(msg string) bool
+}
+`)
+	removed := writeIdl(t, dir, "removed.idl", `
+struct User {
+	id string
+}
+
+interface UserService {
+}
+`)
+
+	for _, c := range []struct {
+		args   []string
+		status int
+		out    string
+	}{
+		// adding functions and interfaces only breaks servers, which
+		// doesn't fail by default
+		{[]string{old, added}, 0, "BREAKING (servers)"},
+		{[]string{"-breaks", "servers", old, added}, 1, "BREAKING (servers)"},
+		{[]string{"-breaks", "both", old, added}, 1, "BREAKING (servers)"},
+		{[]string{old, removed}, 1, "BREAKING (clients)"},
+		{[]string{old, old}, 0, ""},
+		{[]string{old}, 2, ""},
+		{[]string{"-breaks", "nobody", old, added}, 2, ""},
+		{[]string{old, filepath.Join(dir, "missing.idl")}, 2, ""},
+	} {
+		out := &bytes.Buffer{}
+		errOut := &bytes.Buffer{}
+		status := run(c.args, out, errOut)
+		if status != c.status {
+			t.Errorf("%v: expected status %d, got %d: %s%s", c.args, c.status, status, out, errOut)
+		}
+		if !strings.Contains(out.String(), c.out) {
+			t.Errorf("%v: expected output to contain %q, got: %s", c.args, c.out, out)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/coopernurse/barrister-go"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var format string
+	var out string
+	var title string
+	var version string
+	var allOf bool
+
+	flag.StringVar(&format, "f", "html", `Output format. Valid values: "html", "markdown", "openrpc", "jsonschema"`)
+	flag.StringVar(&out, "o", "", `Output location. For html, the directory to write the site to (default "doc"). For other formats, the file to write (default STDOUT)`)
+	flag.StringVar(&title, "t", "", "Title of the documentation (default: IDL file name)")
+	flag.StringVar(&version, "version", "", "API version to write to the OpenRPC info (default: IDL checksum)")
+	flag.BoolVar(&allOf, "allof", false, `Write structs that extend another struct using "allOf" instead of copying the inherited fields (openrpc and jsonschema only)`)
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: barrister-doc [jsonfile | idlfile]\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	if format != "html" && format != "markdown" && format != "openrpc" && format != "jsonschema" {
+		fmt.Fprintf(os.Stderr, `Invalid value %q for flag "f". Valid values: "html", "markdown", "openrpc", "jsonschema".`+"\n", format)
+		os.Exit(1)
+	}
+
+	filename := flag.Arg(0)
+	idl, err := barrister.LoadIdlFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading IDL from %s: %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	if title == "" {
+		base := filepath.Base(filename)
+		title = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	if version == "" {
+		version = idl.Meta.Checksum
+	}
+
+	switch format {
+	case "markdown":
+		writeOutput(out, idl.GenerateMarkdown(title))
+		return
+	case "openrpc":
+		writeOutput(out, marshalIndent(idl.ExportOpenRpc(title, version, allOf)))
+		return
+	case "jsonschema":
+		writeOutput(out, marshalIndent(idl.ExportJsonSchema(allOf)))
+		return
+	}
+
+	if out == "" {
+		out = "doc"
+	}
+	err = os.MkdirAll(out, 0755)
+	if err != nil {
+		panic(err)
+	}
+	for name, page := range idl.GenerateHtml(title) {
+		writeFile(filepath.Join(out, name), page)
+	}
+}
+
+func marshalIndent(v interface{}) []byte {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return append(b, '\n')
+}
+
+// writeOutput writes b to filename, or to STDOUT if filename is empty
+func writeOutput(filename string, b []byte) {
+	if filename == "" {
+		os.Stdout.Write(b)
+		return
+	}
+	writeFile(filename, b)
+}
+
+func writeFile(filename string, b []byte) {
+	err := ioutil.WriteFile(filename, b, 0644)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("Wrote: %s\n", filename)
+}
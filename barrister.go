@@ -7,9 +7,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"net/http"
+	"path"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -45,7 +48,7 @@ func ParseIdlJson(jsonData []byte) (*Idl, error) {
 		return nil, err
 	}
 
-	return NewIdl(elems), nil
+	return NewValidatedIdl(elems)
 }
 
 // MustParseIdlJson calls ParseIdlJson and panics if an error is returned
@@ -58,7 +61,8 @@ func MustParseIdlJson(jsonData []byte) *Idl {
 }
 
 // NewIdl creates a new Idl struct based on the slice of elements
-// parsed from the IDL JSON document
+// parsed from the IDL JSON document.  The elements are not validated,
+// see NewValidatedIdl.
 func NewIdl(elems []IdlJsonElem) *Idl {
 	idl := &Idl{
 		elems:      elems,
@@ -80,7 +84,7 @@ func NewIdl(elems []IdlJsonElem) *Idl {
 			}
 			idl.interfaces[el.Name] = funcs
 		} else if el.Type == "struct" {
-			idl.structs[el.Name] = &Struct{Name: el.Name, Extends: el.Extends, Fields: el.Fields}
+			idl.structs[el.Name] = &Struct{Name: el.Name, Extends: el.Extends, Fields: el.Fields, Comment: el.Comment}
 		} else if el.Type == "enum" {
 			idl.enums[el.Name] = el.Values
 		}
@@ -132,6 +136,7 @@ type Struct struct {
 	Name    string
 	Extends string
 	Fields  []Field
+	Comment string
 
 	// fields in this struct, and its parents
 	allFields []Field
@@ -311,15 +316,18 @@ type Idl struct {
 
 func (idl *Idl) computeAllStructFields() {
 	for _, s := range idl.structs {
-		s.allFields = idl.computeStructFields(s, []Field{})
+		s.allFields = idl.computeStructFields(s, []Field{}, map[string]bool{})
 	}
 }
 
-func (idl *Idl) computeStructFields(toAdd *Struct, allFields []Field) []Field {
+// computeStructFields appends the fields of toAdd and its parents to allFields.
+// seen holds the structs already visited so that cyclic inheritance terminates.
+func (idl *Idl) computeStructFields(toAdd *Struct, allFields []Field, seen map[string]bool) []Field {
+	seen[toAdd.Name] = true
 	if toAdd.Extends != "" {
 		parent, ok := idl.structs[toAdd.Extends]
-		if ok {
-			allFields = idl.computeStructFields(parent, allFields)
+		if ok && !seen[parent.Name] {
+			allFields = idl.computeStructFields(parent, allFields, seen)
 		}
 	}
 
@@ -346,13 +354,15 @@ const (
 // the Go package names and values are the source code for that package.
 //
 // Typically you'll use the idl2go binary as a front end to this method, but this method is exposed
-// if you wish to write your own code generation tooling.
+// if you wish to write your own code generation tooling.  Custom generators can also walk the IDL
+// directly using Interfaces, Structs, Enums and TypeKind.
 //
 // defaultPkgName - Go package name to use for non-namespaced elements.  Since interfaces are never
 // namespaced in Barrister, all interfaces will be generated into this package.
 //
 // baseImport - Base Go import path to use for internal imports.  For example, if "myproject" is provided,
-// and two packages "usersvc" and "common" are resolved, then "usersvc" will import "myproject/common"
+// and two packages "usersvc" and "common" are resolved, then "usersvc" will import "myproject/common".
+// Use GenerateOptions.Packages to import namespaces from other paths.
 //
 // optionalToPtr - If true struct fields marked `[optional]` will be generated as Go pointers.  If false,
 // they will be generated as non-pointer types with `omitempty` in the JSON tag.  Note: due to the
@@ -363,19 +373,208 @@ const (
 // If "no", they won't. If "both", two interfaces will be generated, one
 // with a Context argument and another without.
 //
+// The generated code is formatted with go/format, and generating code from the same
+// IDL always produces the same bytes.  GenerateGo panics if the generated code is not
+// valid Go, see GenerateGoSource.
+//
 func (idl *Idl) GenerateGo(defaultPkgName string, baseImport string, optionalToPtr bool, includeContext IncludeContext) map[string][]byte {
+	pkgNameToGoCode, err := idl.GenerateGoSource(defaultPkgName, baseImport, optionalToPtr, includeContext)
+	if err != nil {
+		panic(err)
+	}
+	return pkgNameToGoCode
+}
+
+// GenerateGoSource is like GenerateGo, but returns a *GenerateError instead of
+// panicking if the code generated for a package is not valid Go, which can happen
+// if IDL names clash with Go keywords or with each other once capitalized.
+func (idl *Idl) GenerateGoSource(defaultPkgName string, baseImport string, optionalToPtr bool, includeContext IncludeContext) (map[string][]byte, error) {
+	return idl.GenerateGoOptions(GenerateOptions{
+		DefaultPkgName: defaultPkgName,
+		BaseImport:     baseImport,
+		OptionalToPtr:  optionalToPtr,
+		IncludeContext: includeContext,
+	})
+}
+
+// GenerateOptions holds the settings for Idl.GenerateGoOptions.  See GenerateGo
+// for a description of the settings it shares with GenerateGo.
+type GenerateOptions struct {
+	DefaultPkgName string         `json:"default_pkg_name"`
+	BaseImport     string         `json:"base_import"`
+	OptionalToPtr  bool           `json:"optional_to_ptr"`
+	IncludeContext IncludeContext `json:"include_context"`
+
+	// If true, a mock and an in-memory fake client are generated for each
+	// interface, for use in tests.  For interface "Calculator" these are:
+	//
+	// CalculatorMock - implements Calculator by calling the optional
+	// AddFunc, etc. fields and records the args of each call
+	//
+	// NewCalculatorFakeClient - returns a client that invokes a Calculator
+	// through an in-memory barrister.Server, so requests and responses are
+	// serialized as they would be over a real transport
+	Mocks bool `json:"mocks"`
+
+	// Optional mapping of IDL types and fields to existing Go types
+	Types *TypeMapping `json:"types,omitempty"`
+
+	// Optional import paths of the Go packages of IDL namespaces, keyed by
+	// namespace, e.g. {"inc": "example.com/shared/inc"}.  The package name
+	// is the last element of the path, unless given after a ";", e.g.
+	// "example.com/shared/inc/v2;inc".  No code is generated for these
+	// namespaces.  Other namespaces are imported from BaseImport + namespace.
+	Packages map[string]string `json:"packages,omitempty"`
+
+	// Import path of the barrister package used by generated code, e.g. to
+	// use a fork.  Defaults to DefaultRuntimeImport.
+	RuntimeImport string `json:"runtime_import,omitempty"`
+
+	// Optional templates, parsed after the default templates, so they can
+	// redefine them, e.g. {{define "struct"}}...{{end}}.  *.tmpl files in
+	// the root of Templates are parsed.  Files with two extensions, e.g.
+	// "extra.go.tmpl", are also executed with a TemplateData to generate
+	// another file in each package, see GenerateGoFiles.
+	Templates fs.FS `json:"-"`
+}
+
+// DefaultRuntimeImport is the import path of this package, which generated
+// code imports unless GenerateOptions.RuntimeImport is set
+const DefaultRuntimeImport = "github.com/coopernurse/barrister-go"
+
+// runtimeImport returns the import path of the barrister package
+func (opts GenerateOptions) runtimeImport() string {
+	if opts.RuntimeImport == "" {
+		return DefaultRuntimeImport
+	}
+	return opts.RuntimeImport
+}
+
+// nsPackage returns the import path and name of the Go package of IDL namespace ns
+func (opts GenerateOptions) nsPackage(ns string) (string, string) {
+	if p, ok := opts.Packages[ns]; ok {
+		if i := strings.LastIndex(p, ";"); i > -1 {
+			return p[:i], p[i+1:]
+		}
+		return p, path.Base(p)
+	}
+	if opts.BaseImport == "" || strings.HasSuffix(opts.BaseImport, "/") {
+		return opts.BaseImport + ns, ns
+	}
+	return opts.BaseImport + "/" + ns, ns
+}
+
+// checkPackages returns an error if opts.Packages maps a namespace that is
+// not in idl, or gives an invalid package name
+func (opts GenerateOptions) checkPackages(idl *Idl) error {
+	namespaces := map[string]bool{}
+	for _, el := range idl.elems {
+		if ns, _ := splitNs(el.Name); ns != "" {
+			namespaces[ns] = true
+		}
+	}
+	mapped := make([]string, 0, len(opts.Packages))
+	for ns := range opts.Packages {
+		mapped = append(mapped, ns)
+	}
+	sort.Strings(mapped)
+	for _, ns := range mapped {
+		if !namespaces[ns] {
+			return fmt.Errorf("barrister: packages: IDL has no namespace: %s", ns)
+		}
+		p, name := opts.nsPackage(ns)
+		if p == "" || !isGoIdent(name) {
+			return fmt.Errorf("barrister: packages: invalid package for namespace %s: %s", ns, opts.Packages[ns])
+		}
+	}
+	return nil
+}
+
+// isGoIdent returns true if s is a Go identifier that is not a keyword
+func isGoIdent(s string) bool {
+	if s == "" || !isIdentStart(s[0]) || escReserved(s) != s {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isIdentStart(s[i]) && (s[i] < '0' || s[i] > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// GenerateGoOptions is like GenerateGoSource, with settings passed in opts.
+// Files generated by templates added with GenerateOptions.Templates are not
+// returned, see GenerateGoFiles.
+func (idl *Idl) GenerateGoOptions(opts GenerateOptions) (map[string][]byte, error) {
 	pkgNameToGoCode := make(map[string][]byte)
-	for _, nsIdl := range partitionIdlByNamespace(idl, defaultPkgName) {
+	err := idl.generateGoFiles(opts, func(pkgName string, name string, code []byte) {
+		if name == pkgName+".go" {
+			pkgNameToGoCode[pkgName] = code
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pkgNameToGoCode, nil
+}
+
+// GenerateGoFiles is like GenerateGoOptions, but also returns the files
+// generated by templates added with GenerateOptions.Templates.  A map is
+// returned whose keys are file paths, separated by "/", relative to the
+// output directory, e.g. "usersvc/usersvc.go", and values are their contents.
+func (idl *Idl) GenerateGoFiles(opts GenerateOptions) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := idl.generateGoFiles(opts, func(pkgName string, name string, code []byte) {
+		files[pkgName+"/"+name] = code
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// generateGoFiles calls add with each file generated for each package
+func (idl *Idl) generateGoFiles(opts GenerateOptions, add func(pkgName string, name string, code []byte)) error {
+	if opts.Types != nil {
+		err := opts.Types.check(idl)
+		if err != nil {
+			return err
+		}
+	}
+	err := opts.checkPackages(idl)
+	if err != nil {
+		return err
+	}
+	err = checkGoNames(idl, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, nsIdl := range partitionIdlByNamespace(idl, opts.DefaultPkgName) {
+		if _, ok := opts.Packages[nsIdl.pkgName]; ok {
+			continue
+		}
 		g := generateGo{idl,
 			nsIdl.idl,
 			nsIdl.pkgName,
-			optionalToPtr,
-			includeContext,
+			opts.OptionalToPtr,
+			opts.IncludeContext,
 			nsIdl.imports,
-			baseImport}
-		pkgNameToGoCode[nsIdl.pkgName] = g.generate()
+			opts.Mocks,
+			opts.Types,
+			nil,
+			opts,
+			nil}
+		files, err := g.generate()
+		if err != nil {
+			return err
+		}
+		for name, code := range files {
+			add(nsIdl.pkgName, name, code)
+		}
 	}
-	return pkgNameToGoCode
+	return nil
 }
 
 // Method returns the Function related to the given method.
@@ -411,11 +610,18 @@ func partitionIdlByNamespace(idl *Idl, defaultPkgName string) []namespacedIdl {
 		}
 	}
 
+	pkgs := make([]string, 0, len(pkgNameToIdlElems))
+	for pkg := range pkgNameToIdlElems {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
 	nsIdl := make([]namespacedIdl, 0)
-	for pkg, elems := range pkgNameToIdlElems {
-		elems = append(elems, metaElem)
+	for _, pkg := range pkgs {
+		elems := append(pkgNameToIdlElems[pkg], metaElem)
 		idl := NewIdl(elems)
 		imports := findAllImports(pkg, elems)
+		sort.Strings(imports)
 		nsIdl = append(nsIdl, namespacedIdl{idl, pkg, imports})
 	}
 	return nsIdl
@@ -495,6 +701,10 @@ type JsonRpcRequest struct {
 
 	// Parameter values to be used during the invocation of the method
 	Params interface{} `json:"params"`
+
+	// Optional checksum of the IDL the client was generated from.
+	// Not part of JSON-RPC 2.0, see WithChecksum.
+	Checksum string `json:"checksum,omitempty"`
 }
 
 // JsonRpcError represents a JSON-RPC 2.0 Error
@@ -687,6 +897,21 @@ type TransportContext interface {
 	SendContext(ctx context.Context, in []byte) ([]byte, error)
 }
 
+// InMemoryTransport sends requests directly to a Server in the same process,
+// which is useful in tests.  Each request is passed empty Headers.
+type InMemoryTransport struct {
+	Server *Server
+}
+
+func (t *InMemoryTransport) Send(in []byte) ([]byte, error) {
+	return t.SendContext(context.Background(), in)
+}
+
+func (t *InMemoryTransport) SendContext(ctx context.Context, in []byte) ([]byte, error) {
+	headers := Headers{Request: map[string][]string{}, Response: map[string][]string{}}
+	return t.Server.InvokeBytesContext(ctx, headers, in), nil
+}
+
 // HttpTransport sends requests via the Go `http` package
 type HttpTransport struct {
 	// Endpoint of JSON-RPC service to consume
@@ -721,6 +946,12 @@ func (t *HttpTransport) Send(in []byte) ([]byte, error) {
 }
 
 func (t *HttpTransport) SendContext(ctx context.Context, in []byte) ([]byte, error) {
+	return t.SendChecksumContext(ctx, in, "")
+}
+
+// SendChecksumContext is like SendContext, and sends checksum in the
+// ChecksumHeader request header if it is not empty
+func (t *HttpTransport) SendChecksumContext(ctx context.Context, in []byte, checksum string) ([]byte, error) {
 	req, err := http.NewRequest("POST", t.Url, bytes.NewBuffer(in))
 	if err != nil {
 		return nil, fmt.Errorf("barrister: HttpTransport NewRequest failed: %s", err)
@@ -729,6 +960,9 @@ func (t *HttpTransport) SendContext(ctx context.Context, in []byte) ([]byte, err
 
 	// TODO: need to make mime type plugable
 	req.Header.Add("Content-Type", "application/json")
+	if checksum != "" {
+		req.Header.Set(ChecksumHeader, checksum)
+	}
 
 	if t.Hook != nil {
 		t.Hook.Before(req, in)
@@ -784,9 +1018,81 @@ type ClientContext interface {
 	CallBatchContext(ctx context.Context, batch []JsonRpcRequest) []JsonRpcResponse
 }
 
+// RawClient is implemented by Clients that can return the JSON-RPC result
+// without decoding it, so callers can unmarshal it directly into its Go type.
+// RemoteClient implements RawClient.
+type RawClient interface {
+	// CallRawContext is like ClientContext.CallContext, but returns the
+	// undecoded result
+	CallRawContext(ctx context.Context, method string, params ...interface{}) (json.RawMessage, error)
+}
+
+// CallInto invokes method with c and stores the result in target, which must
+// be a pointer to the Go type of the method's return value.  Generated proxies
+// use CallInto.
+//
+// If c implements RawClient, the result is decoded directly into target and
+// checked against the IDL as it is decoded, which preserves int64 values
+// above 2^53 and avoids converting the result twice.  Otherwise the result of
+// Call is converted with Convert.  Results that are not valid for the IDL are
+// reported as a *JsonRpcError with code -32000.
+//
+// ctx is ignored if c does not implement ClientContext.
+func CallInto(ctx context.Context, c Client, idl *Idl, method string, target interface{}, params ...interface{}) error {
+	targetVal := reflect.ValueOf(target).Elem()
+	retType := idl.Method(method).Returns
+
+	var res interface{}
+	var err error
+	if rc, ok := c.(RawClient); ok {
+		var raw json.RawMessage
+		raw, err = rc.CallRawContext(ctx, method, params...)
+		if err != nil {
+			return err
+		}
+		if len(raw) > 0 {
+			err = decodeValue(idl, &retType, raw, target, "")
+			if err != nil {
+				msg := fmt.Sprintf("barrister: %s: invalid result: %s", method, err)
+				return &JsonRpcError{Code: -32000, Message: msg}
+			}
+			return nil
+		}
+	} else if cc, ok := c.(ClientContext); ok {
+		res, err = cc.CallContext(ctx, method, params...)
+	} else {
+		res, err = c.Call(method, params...)
+	}
+	if err != nil {
+		return err
+	}
+
+	conv, err := Convert(idl, &retType, targetVal.Type(), res, "")
+	if err != nil {
+		msg := fmt.Sprintf("barrister: %s: invalid result: %s", method, err)
+		return &JsonRpcError{Code: -32000, Message: msg}
+	}
+	if conv == nil {
+		targetVal.Set(reflect.Zero(targetVal.Type()))
+		return nil
+	}
+
+	convVal := reflect.ValueOf(conv)
+	if !convVal.Type().AssignableTo(targetVal.Type()) {
+		msg := fmt.Sprintf("%s returned invalid type: %v", method, convVal.Type())
+		return &JsonRpcError{Code: -32000, Message: msg}
+	}
+	targetVal.Set(convVal)
+	return nil
+}
+
 // NewRemoteClient creates a RemoteClient with the given Transport using the JsonSerializer
-func NewRemoteClient(trans Transport, forceASCII bool) Client {
-	return &RemoteClient{transportIgnoreContext{trans}, &JsonSerializer{forceASCII}}
+func NewRemoteClient(trans Transport, forceASCII bool, opts ...ClientOption) Client {
+	transCtx, ok := trans.(TransportContext)
+	if !ok {
+		transCtx = transportIgnoreContext{trans}
+	}
+	return NewRemoteClientContext(transCtx, forceASCII, opts...)
 }
 
 type transportIgnoreContext struct {
@@ -798,14 +1104,37 @@ func (t transportIgnoreContext) SendContext(ctx context.Context, in []byte) ([]b
 }
 
 // NewRemoteClientContext creates a RemoteClient with the given TransportContext using the JsonSerializer
-func NewRemoteClientContext(trans TransportContext, forceASCII bool) ClientContext {
-	return &RemoteClient{trans, &JsonSerializer{forceASCII}}
+func NewRemoteClientContext(trans TransportContext, forceASCII bool, opts ...ClientOption) ClientContext {
+	c := &RemoteClient{Trans: trans, Ser: &JsonSerializer{ForceASCII: forceASCII}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // RemoteClient implements Client against the given Transport and Serializer.
 type RemoteClient struct {
 	Trans TransportContext
 	Ser   Serializer
+
+	// Optional checksum of the IDL the client was generated from, sent with
+	// each request so the server can detect mismatched contracts
+	Checksum string
+}
+
+// checksumInBody returns true if Checksum must be sent in the JSON-RPC
+// request because the transport can't send it out of band
+func (c *RemoteClient) checksumInBody() bool {
+	_, ok := c.Trans.(ChecksumTransport)
+	return c.Checksum != "" && !ok
+}
+
+func (c *RemoteClient) sendContext(ctx context.Context, in []byte) ([]byte, error) {
+	ct, ok := c.Trans.(ChecksumTransport)
+	if ok && c.Checksum != "" {
+		return ct.SendChecksumContext(ctx, in, c.Checksum)
+	}
+	return c.Trans.SendContext(ctx, in)
 }
 
 func (c *RemoteClient) CallBatch(batch []JsonRpcRequest) []JsonRpcResponse {
@@ -813,6 +1142,17 @@ func (c *RemoteClient) CallBatch(batch []JsonRpcRequest) []JsonRpcResponse {
 }
 
 func (c *RemoteClient) CallBatchContext(ctx context.Context, batch []JsonRpcRequest) []JsonRpcResponse {
+	if c.checksumInBody() {
+		withChecksum := make([]JsonRpcRequest, len(batch))
+		for i, req := range batch {
+			if req.Checksum == "" {
+				req.Checksum = c.Checksum
+			}
+			withChecksum[i] = req
+		}
+		batch = withChecksum
+	}
+
 	reqBytes, err := c.Ser.Marshal(batch)
 	if err != nil {
 		msg := fmt.Sprintf("barrister: CallBatch unable to Marshal request: %s", err)
@@ -820,7 +1160,7 @@ func (c *RemoteClient) CallBatchContext(ctx context.Context, batch []JsonRpcRequ
 			JsonRpcResponse{Error: &JsonRpcError{Code: -32600, Message: msg}}}
 	}
 
-	respBytes, err := c.Trans.SendContext(ctx, reqBytes)
+	respBytes, err := c.sendContext(ctx, reqBytes)
 	if err != nil {
 		msg := fmt.Sprintf("barrister: CallBatch Transport error during request: %s", err)
 		return []JsonRpcResponse{
@@ -843,32 +1183,57 @@ func (c *RemoteClient) Call(method string, params ...interface{}) (interface{},
 }
 
 func (c *RemoteClient) CallContext(ctx context.Context, method string, params ...interface{}) (interface{}, error) {
+	var rpcResp JsonRpcResponse
+	err := c.call(ctx, method, params, &rpcResp, &rpcResp.Error)
+	if err != nil {
+		return nil, err
+	}
+	return rpcResp.Result, nil
+}
+
+// CallRawContext implements RawClient
+func (c *RemoteClient) CallRawContext(ctx context.Context, method string, params ...interface{}) (json.RawMessage, error) {
+	var rpcResp struct {
+		Error  *JsonRpcError   `json:"error,omitempty"`
+		Result json.RawMessage `json:"result,omitempty"`
+	}
+	err := c.call(ctx, method, params, &rpcResp, &rpcResp.Error)
+	if err != nil {
+		return nil, err
+	}
+	return rpcResp.Result, nil
+}
+
+// call sends a request for method and unmarshals the response into rpcResp.
+// rpcErr points at the Error field of rpcResp.
+func (c *RemoteClient) call(ctx context.Context, method string, params []interface{}, rpcResp interface{}, rpcErr **JsonRpcError) error {
 	rpcReq := JsonRpcRequest{Jsonrpc: "2.0", Id: randHex(20), Method: method, Params: params}
+	if c.checksumInBody() {
+		rpcReq.Checksum = c.Checksum
+	}
 
 	reqBytes, err := c.Ser.Marshal(rpcReq)
 	if err != nil {
 		msg := fmt.Sprintf("barrister: %s: Call unable to Marshal request: %s", method, err)
-		return nil, &JsonRpcError{Code: -32600, Message: msg}
+		return &JsonRpcError{Code: -32600, Message: msg}
 	}
 
-	respBytes, err := c.Trans.SendContext(ctx, reqBytes)
+	respBytes, err := c.sendContext(ctx, reqBytes)
 	if err != nil {
 		msg := fmt.Sprintf("barrister: %s: Transport error during request: %s", method, err)
-		return nil, &JsonRpcError{Code: -32603, Message: msg}
+		return &JsonRpcError{Code: -32603, Message: msg}
 	}
 
-	var rpcResp JsonRpcResponse
-	err = c.Ser.Unmarshal(respBytes, &rpcResp)
+	err = c.Ser.Unmarshal(respBytes, rpcResp)
 	if err != nil {
 		msg := fmt.Sprintf("barrister: %s: Call unable to Unmarshal response: %s", method, err)
-		return nil, &JsonRpcError{Code: -32603, Message: msg}
+		return &JsonRpcError{Code: -32603, Message: msg}
 	}
 
-	if rpcResp.Error != nil {
-		return nil, rpcResp.Error
+	if *rpcErr != nil {
+		return *rpcErr
 	}
-
-	return rpcResp.Result, nil
+	return nil
 }
 
 //////////////////////////////////////////////////
@@ -995,17 +1360,21 @@ func NewJSONServer(idl *Idl, forceASCII bool) Server {
 
 // NewServer creates a Server for the given IDL and Serializer
 func NewServer(idl *Idl, ser Serializer) Server {
-	return Server{idl, ser, map[string]interface{}{}, make([]Filter, 0)}
+	return Server{idl, ser, map[string]interface{}{}, map[string]Dispatcher{}, make([]Filter, 0), ChecksumIgnore, false}
 }
 
 // Server represents a handler for Barrister IDL file.
 // Each Server has one or more handlers (one per interface in the IDL) and
 // zero or more Filters.
 type Server struct {
-	idl      *Idl
-	ser      Serializer
-	handlers map[string]interface{}
-	filters  []Filter
+	idl         *Idl
+	ser         Serializer
+	handlers    map[string]interface{}
+	dispatchers map[string]Dispatcher
+	filters     []Filter
+
+	checksumPolicy  ChecksumPolicy
+	validateResults bool
 }
 
 // AddFilter registers a Filter implementation with the Server.
@@ -1029,6 +1398,14 @@ func firstIsContext(fnType reflect.Type) bool {
 // method panics instead of returning na error if any IDL mismatches are
 // found.
 func (s *Server) AddHandler(iface string, impl interface{}) {
+	s.validateImpl(iface, impl)
+	s.handlers[iface] = impl
+	delete(s.dispatchers, iface)
+}
+
+// validateImpl panics if impl does not implement the IDL interface.  Called
+// by AddHandler and AddDispatcher.
+func (s *Server) validateImpl(iface string, impl interface{}) {
 	ifaceFuncs, ok := s.idl.interfaces[iface]
 
 	if !ok {
@@ -1080,15 +1457,13 @@ func (s *Server) AddHandler(iface string, impl interface{}) {
 			panic(msg)
 		}
 	}
-
-	s.handlers[iface] = impl
 }
 
 // validate ensurse that the given implType matches the expected IDL type.
 // If the type does not match, validate panics.
 //
-// This method is called by AddHandler to ensure that registered implementations
-// comply with the IDL.
+// This method is called by validateImpl to ensure that registered
+// implementations comply with the IDL.
 func (s *Server) validate(idlField Field, implType reflect.Type, path string) {
 	testVal := idlField.testVal(s.idl)
 	conv := newConvert(s.idl, &idlField, implType, testVal, "")
@@ -1116,15 +1491,21 @@ func (s *Server) InvokeBytesContext(ctx context.Context, headers Headers, req []
 
 	// batch execution
 	if batch {
-		var batchReq []JsonRpcRequest
+		var batchReq []json.RawMessage
 		batchResp := []JsonRpcResponse{}
 		err := s.ser.Unmarshal(req, &batchReq)
 		if err != nil {
 			return jsonParseErr("", true, err)
 		}
 
-		for _, req := range batchReq {
-			resp := s.InvokeOne(headers, &req)
+		for _, raw := range batchReq {
+			// params are left as raw JSON, see requestParams
+			rpcReq := JsonRpcRequest{Params: &json.RawMessage{}}
+			err := s.ser.Unmarshal(raw, &rpcReq)
+			if err != nil {
+				return jsonParseErr("", true, err)
+			}
+			resp := s.InvokeOneContext(ctx, headers, &rpcReq)
 			batchResp = append(batchResp, *resp)
 		}
 
@@ -1135,8 +1516,8 @@ func (s *Server) InvokeBytesContext(ctx context.Context, headers Headers, req []
 		return b
 	}
 
-	// single request execution
-	rpcReq := JsonRpcRequest{}
+	// single request execution - params are left as raw JSON, see requestParams
+	rpcReq := JsonRpcRequest{Params: &json.RawMessage{}}
 	err := s.ser.Unmarshal(req, &rpcReq)
 	if err != nil {
 		return jsonParseErr("", false, err)
@@ -1153,6 +1534,9 @@ func (s *Server) InvokeBytesContext(ctx context.Context, headers Headers, req []
 
 // InvokeOne handles a single JSON-RPC request, delegating to Call.  If the special "barrister-idl"
 // method is handled, InvokeOne will return the IDL associated with this Server.
+//
+// If the request carries an IDL checksum, either in the JsonRpcRequest or the ChecksumHeader
+// header, it is checked according to the Server ChecksumPolicy.
 func (s *Server) InvokeOne(headers Headers, rpcReq *JsonRpcRequest) *JsonRpcResponse {
 	return s.InvokeOneContext(context.Background(), headers, rpcReq)
 }
@@ -1164,16 +1548,14 @@ func (s *Server) InvokeOneContext(ctx context.Context, headers Headers, rpcReq *
 		return &JsonRpcResponse{Jsonrpc: "2.0", Id: rpcReq.Id, Result: s.idl.elems}
 	}
 
-	// handle normal RPC method executions
-	var result interface{}
-	var err error
-	arr, ok := rpcReq.Params.([]interface{})
-	if ok {
-		result, err = s.CallContext(ctx, headers, rpcReq.Method, arr...)
-	} else {
-		result, err = s.CallContext(ctx, headers, rpcReq.Method)
+	ctx, err := s.checkChecksum(ctx, headers, rpcReq)
+	if err != nil {
+		return &JsonRpcResponse{Jsonrpc: "2.0", Id: rpcReq.Id, Error: toJsonRpcError(rpcReq.Method, err)}
 	}
 
+	// handle normal RPC method executions
+	result, err := s.CallContext(ctx, headers, rpcReq.Method, s.requestParams(rpcReq)...)
+
 	if err == nil {
 		// successful Call
 		return &JsonRpcResponse{Jsonrpc: "2.0", Id: rpcReq.Id, Result: result}
@@ -1256,6 +1638,11 @@ func (s *Server) CallContext(ctx context.Context, headers Headers, method string
 		handler = c.CloneForReq(headers)
 	}
 
+	d, ok := s.dispatchers[iface]
+	if ok {
+		return s.dispatch(ctx, headers, method, idlFunc, handler, d, params)
+	}
+
 	elem := reflect.ValueOf(handler)
 	fn := elem.MethodByName(fname)
 	if fn == zeroVal {
@@ -1291,18 +1678,18 @@ func (s *Server) CallContext(ctx context.Context, headers Headers, method string
 		Handler: handler,
 	}
 
-	// run filters - PreInvoke
-	flen := len(s.filters)
-	for i := 0; i < flen; i++ {
-		ok := s.filters[i].PreInvoke(rr)
-		if !ok {
-			return rr.Result, rr.Err
-		}
+	if !s.preInvoke(rr) {
+		return rr.Result, rr.Err
+	}
+
+	if len(idlFunc.Params) != len(rr.Params) {
+		return nil, &JsonRpcError{Code: -32602,
+			Message: fmt.Sprintf("Method %s expects %d params but was passed %d", method, len(idlFunc.Params), len(rr.Params))}
 	}
 
-	// convert params
+	// convert params, including changes made by filters
 	paramVals := []reflect.Value{}
-	for x, param := range params {
+	for x, param := range rr.Params {
 		arg := x
 		if firstCtx {
 			arg++
@@ -1338,18 +1725,132 @@ func (s *Server) CallContext(ctx context.Context, headers Headers, method string
 			rr.Err = e
 		}
 	}
+	s.checkResult(idlFunc, rr)
 
-	// run filters - PostInvoke
-	for i := flen - 1; i >= 0; i-- {
-		ok := s.filters[i].PostInvoke(rr)
-		if !ok {
-			break
+	s.postInvoke(rr)
+	return rr.Result, rr.Err
+}
+
+// dispatch is the CallContext implementation for handlers registered with
+// AddDispatcher.  Filters are run at the same points as for other handlers.
+func (s *Server) dispatch(ctx context.Context, headers Headers, method string, idlFunc Function, handler interface{}, d Dispatcher, params []interface{}) (interface{}, error) {
+	if len(idlFunc.Params) != len(params) {
+		return nil, &JsonRpcError{Code: -32602,
+			Message: fmt.Sprintf("Method %s expects %d params but was passed %d", method, len(idlFunc.Params), len(params))}
+	}
+
+	rr := &RequestResponse{
+		Context: ctx,
+		Headers: headers,
+		Method:  method,
+		Params:  params,
+		Handler: handler,
+	}
+
+	// filters see the params as they do for other handlers, and may
+	// replace them
+	if len(s.filters) > 0 {
+		decoded, err := s.genericParams(params)
+		if err != nil {
+			return nil, err
+		}
+		rr.Params = decoded
+		if !s.preInvoke(rr) {
+			return rr.Result, rr.Err
+		}
+		if len(idlFunc.Params) != len(rr.Params) {
+			return nil, &JsonRpcError{Code: -32602,
+				Message: fmt.Sprintf("Method %s expects %d params but was passed %d", method, len(idlFunc.Params), len(rr.Params))}
 		}
 	}
 
+	call, err := d.Decode(s.idl, method, rr.Params)
+	if err != nil {
+		return nil, err
+	}
+	rr.Result, rr.Err = call(rr.Context, handler)
+	s.checkResult(idlFunc, rr)
+
+	s.postInvoke(rr)
 	return rr.Result, rr.Err
 }
 
+// SetValidateResults sets whether the values returned by handlers are checked
+// against the IDL before they are passed to filters and sent to the client.
+// Results that implement Validator, such as idl2go generated structs and
+// enums, are checked with their Validate method.
+// An invalid result, e.g. a struct with an enum field that isn't one of the
+// enum values or a nil required array, is replaced with a JsonRpcError with
+// code -32603.  Results are not validated by default.
+func (s *Server) SetValidateResults(validate bool) {
+	s.validateResults = validate
+}
+
+// Validator is implemented by the structs and enums idl2go generates
+type Validator interface {
+	// Validate returns an error, typically a *ValidationError, if the value
+	// is not valid for the IDL
+	Validate() error
+}
+
+// checkResult replaces rr.Result with an error if it is not valid for
+// idlFunc and result validation is enabled.  Results that implement
+// Validator are checked with their Validate method, others are checked
+// against the IDL once marshaled to JSON.
+func (s *Server) checkResult(idlFunc Function, rr *RequestResponse) {
+	if !s.validateResults || rr.Err != nil {
+		return
+	}
+
+	var msg string
+	if v, ok := rr.Result.(Validator); ok && !isNilPtr(rr.Result) {
+		err := v.Validate()
+		if err == nil {
+			return
+		}
+		if ve, ok := ValidationErrorAt("result", err).(*ValidationError); ok {
+			msg = fmt.Sprintf("barrister: %s: invalid %s: %s", rr.Method, ve.Path, ve.Msg)
+		} else {
+			msg = fmt.Sprintf("barrister: %s: invalid result: %s", rr.Method, err)
+		}
+	} else {
+		err := checkGoValue(s.idl, &idlFunc.Returns, rr.Result, "result")
+		te, ok := err.(*typeError)
+		if !ok {
+			return
+		}
+		msg = fmt.Sprintf("barrister: %s: invalid %s: %s", rr.Method, te.path, te.msg)
+	}
+	rr.Result, rr.Err = nil, &JsonRpcError{Code: -32603, Message: msg}
+}
+
+// isNilPtr returns true if v is a nil pointer, whose value methods can't be
+// called
+func isNilPtr(v interface{}) bool {
+	val := reflect.ValueOf(v)
+	return val.Kind() == reflect.Ptr && val.IsNil()
+}
+
+// preInvoke runs Filter.PreInvoke in order of registration and returns
+// false if a filter terminated the request
+func (s *Server) preInvoke(rr *RequestResponse) bool {
+	for _, f := range s.filters {
+		if !f.PreInvoke(rr) {
+			return false
+		}
+	}
+	return true
+}
+
+// postInvoke runs Filter.PostInvoke in reverse order of registration
+func (s *Server) postInvoke(rr *RequestResponse) {
+	for i := len(s.filters) - 1; i >= 0; i-- {
+		if !s.filters[i].PostInvoke(rr) {
+			break
+		}
+	}
+}
+
 // ServeHTTP handles HTTP requests for the server.
 func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	buf := bytes.Buffer{}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/coopernurse/barrister-go"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const usage = `Usage:
+  barrister [flags] list [url] [interface | interface.method | type]
+  barrister [flags] call url interface.method [args...]
+
+list prints the interfaces and methods of the service at url, with their
+comments.  Given a method or type, it also prints the structs and enums it uses.
+
+call invokes a method and prints its result.  Each arg is the JSON value of a
+param.  String and enum params may be given without quotes, and struct params
+as key=value args for each field, e.g.:
+
+  barrister call http://localhost:8080/users UserService.save name=Bob address.city=Paris
+
+The IDL is fetched from the service with the barrister-idl method unless -idl
+is given.  url may use any registered transport: %s.
+
+Flags:
+`
+
+func main() {
+	var idlFile string
+	var timeout time.Duration
+	headers := headerFlag{}
+
+	flag.StringVar(&idlFile, "idl", "", "IDL JSON or .idl file of the service, used instead of fetching the IDL from the service")
+	flag.Var(headers, "H", `HTTP header to send with each request, as "Name: value". May be repeated`)
+	flag.DurationVar(&timeout, "timeout", 30*time.Second, "Timeout for each request")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, usage, strings.Join(barrister.TransportSchemes(), ", "))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		args = args[1:]
+		url := ""
+		if len(args) > 0 && strings.Contains(args[0], "://") {
+			url = args[0]
+			args = args[1:]
+		}
+		if len(args) > 1 || (url == "" && idlFile == "") {
+			flag.Usage()
+			os.Exit(1)
+		}
+		var client barrister.ClientContext
+		if url != "" {
+			client = connect(url, headers)
+		}
+		idl := loadIdl(idlFile, client, timeout)
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		err := list(os.Stdout, idl, name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "call":
+		if len(args) < 3 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		client := connect(args[1], headers)
+		idl := loadIdl(idlFile, client, timeout)
+		os.Exit(call(os.Stdout, os.Stderr, client, idl, timeout, args[2], args[3:]))
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n", args[0])
+		flag.Usage()
+		os.Exit(1)
+	}
+}
+
+// connect returns a client for the service at url, using the transport
+// registered for its scheme
+func connect(url string, headers headerFlag) barrister.ClientContext {
+	trans, err := barrister.NewTransport(url)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if len(headers) > 0 {
+		httpTrans, ok := trans.(*barrister.HttpTransport)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "-H is only supported for HTTP URLs, not %s\n", url)
+			os.Exit(1)
+		}
+		httpTrans.Hook = headers
+	}
+	return barrister.NewRemoteClient(trans, false).(barrister.ClientContext)
+}
+
+// loadIdl loads the IDL from idlFile, or if it is empty, from the service
+func loadIdl(idlFile string, client barrister.ClientContext, timeout time.Duration) *barrister.Idl {
+	if idlFile != "" {
+		idl, err := barrister.LoadIdlFile(idlFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading IDL from %s: %s\n", idlFile, err)
+			os.Exit(1)
+		}
+		return idl
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	idl, err := barrister.FetchRemoteIdl(ctx, client)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching IDL from service: %s\n", err)
+		os.Exit(1)
+	}
+	return idl
+}
+
+// call invokes method with the params in args, writes the result to out as
+// indented JSON, or the error to errOut, and returns the exit status
+func call(out io.Writer, errOut io.Writer, client barrister.ClientContext, idl *barrister.Idl, timeout time.Duration, method string, args []string) int {
+	fn, ok := findFunction(idl, method)
+	if !ok {
+		fmt.Fprintf(errOut, "Unknown method %s. Run \"barrister list\" to list the methods\n", method)
+		return 1
+	}
+
+	params, err := parseParams(idl, fn, args)
+	if err != nil {
+		fmt.Fprintf(errOut, "Invalid args for %s: %s\n", method, err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	res, err := barrister.Invoke[json.RawMessage](ctx, client, idl, method, params...)
+	if err != nil {
+		if rpcErr, ok := err.(*barrister.JsonRpcError); ok {
+			fmt.Fprintf(errOut, "Error %d: %s\n", rpcErr.Code, rpcErr.Message)
+			if rpcErr.Data != nil {
+				data, _ := json.MarshalIndent(rpcErr.Data, "", "  ")
+				fmt.Fprintf(errOut, "%s\n", data)
+			}
+		} else {
+			fmt.Fprintf(errOut, "Error: %s\n", err)
+		}
+		return 1
+	}
+
+	if len(res) == 0 {
+		res = json.RawMessage("null")
+	}
+	b := &bytes.Buffer{}
+	err = json.Indent(b, res, "", "  ")
+	if err != nil {
+		fmt.Fprintf(errOut, "Error: invalid result: %s\n", err)
+		return 1
+	}
+	fmt.Fprintln(out, b.String())
+	return 0
+}
+
+// findFunction returns the function of the fully qualified method
+func findFunction(idl *barrister.Idl, method string) (barrister.Function, bool) {
+	pos := strings.Index(method, ".")
+	if pos < 0 {
+		return barrister.Function{}, false
+	}
+	for _, fn := range idl.Functions(method[:pos]) {
+		if fn.Name == method[pos+1:] {
+			return fn, true
+		}
+	}
+	return barrister.Function{}, false
+}
+
+// headerFlag holds the headers given with -H, and adds them to HTTP requests
+type headerFlag http.Header
+
+func (h headerFlag) String() string {
+	return ""
+}
+
+func (h headerFlag) Set(s string) error {
+	pos := strings.Index(s, ":")
+	if pos < 1 {
+		return fmt.Errorf(`expected "Name: value" but got %q`, s)
+	}
+	http.Header(h).Add(strings.TrimSpace(s[:pos]), strings.TrimSpace(s[pos+1:]))
+	return nil
+}
+
+// Before implements barrister.HttpHook
+func (h headerFlag) Before(req *http.Request, body []byte) {
+	for name, values := range h {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+}
+
+// After implements barrister.HttpHook
+func (h headerFlag) After(req *http.Request, resp *http.Response, body []byte) {
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coopernurse/barrister-go"
+)
+
+type Address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type User struct {
+	Id      string   `json:"id"`
+	Name    string   `json:"name"`
+	Age     int64    `json:"age"`
+	Address *Address `json:"address"`
+	Tags    []string `json:"tags"`
+}
+
+type userService struct{}
+
+func (s userService) Save(u User, role string, note string) (string, error) {
+	return u.Name + " in " + u.Address.City + " is " + role, nil
+}
+
+func (s userService) Find(name string) (*User, error) {
+	if name == "nobody" {
+		return nil, nil
+	}
+	if name == "fail" {
+		return nil, &barrister.JsonRpcError{Code: 100, Message: "lookup failed", Data: map[string]string{"name": name}}
+	}
+	return &User{Id: "u1", Name: name, Tags: []string{}}, nil
+}
+
+func (s userService) Link(a User, b User) (bool, error) {
+	return true, nil
+}
+
+func newTestClient() barrister.ClientContext {
+	server := barrister.NewJSONServer(testIdl, false)
+	server.AddHandler("UserService", userService{})
+	return barrister.NewRemoteClient(&barrister.InMemoryTransport{Server: &server}, false).(barrister.ClientContext)
+}
+
+func TestCall(t *testing.T) {
+	client := newTestClient()
+	for _, c := range []struct {
+		args   []string
+		status int
+		out    string
+		errOut string
+	}{
+		{[]string{"UserService.save", "id=1", "name=Bob", "age=3", "address.city=Paris", "tags=[]", "admin", "note"}, 0,
+			"\"Bob in Paris is admin\"\n", ""},
+		{[]string{"UserService.find", "Al"}, 0,
+			"{\n  \"id\": \"u1\",\n  \"name\": \"Al\",\n  \"age\": 0,\n  \"address\": null,\n  \"tags\": []\n}\n", ""},
+		{[]string{"UserService.find", "nobody"}, 0, "null\n", ""},
+		{[]string{"UserService.find", "fail"}, 1, "", "Error 100: lookup failed\n{\n  \"name\": \"fail\"\n}\n"},
+		{[]string{"UserService.save", "name=Bob"}, 1, "",
+			"Error -32602: Method UserService.save expects 3 params but was passed 1\n"},
+		{[]string{"UserService.save", "name=Bob", "boss", "note"}, 1, "", "Error -32602: "},
+		{[]string{"UserService.nope"}, 1, "", "Unknown method UserService.nope."},
+		{[]string{"UserService.find", `"x`}, 1, "", "Invalid args for UserService.find: param name: invalid JSON"},
+	} {
+		out := &bytes.Buffer{}
+		errOut := &bytes.Buffer{}
+		status := call(out, errOut, client, testIdl, time.Second, c.args[0], c.args[1:])
+		if status != c.status || out.String() != c.out || !strings.HasPrefix(errOut.String(), c.errOut) {
+			t.Errorf("%v: expected %d %q %q, got %d %q %q", c.args, c.status, c.out, c.errOut, status, out, errOut)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	for name, expected := range map[string]string{
+		"": `// Manages users
+interface UserService {
+    // saves a user
+    save(u User, role Role, note string) string
+
+    find(name string) User [optional]
+
+    link(a User, b User) bool
+}
+`,
+		"UserService.find": `// Manages users
+interface UserService {
+    find(name string) User [optional]
+}
+
+// A user
+struct User extends Base {
+    id string
+    // full name
+    name string
+    age int
+    address Address [optional]
+    tags []string
+}
+
+struct Base {
+    id string
+}
+
+struct Address {
+    city string
+    zip string [optional]
+}
+`,
+		"Role": "enum Role {\n    admin\n    guest\n}\n",
+	} {
+		b := &bytes.Buffer{}
+		err := list(b, testIdl, name)
+		if err != nil || b.String() != expected {
+			t.Errorf("list %q: expected:\n%s\ngot: %v\n%s", name, expected, err, b)
+		}
+	}
+
+	err := list(&bytes.Buffer{}, testIdl, "Nope")
+	if err == nil || err.Error() != "IDL has no interface, method, struct or enum named Nope" {
+		t.Errorf("Expected error for unknown name, got: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"github.com/coopernurse/barrister-go"
+	"io"
+	"strings"
+)
+
+// list writes the interfaces of idl to w in IDL syntax, with their comments.
+// If name is not empty, only the interface, method, struct or enum with that
+// name is written, followed by the structs and enums it uses.
+func list(w io.Writer, idl *barrister.Idl, name string) error {
+	if name == "" {
+		for i, iface := range idl.Interfaces() {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			writeInterface(w, iface, "")
+		}
+		return nil
+	}
+
+	var used []string
+	wroteIface := true
+	if iface, ok := idl.Interface(name); ok {
+		writeInterface(w, iface, "")
+		for _, fn := range iface.Functions {
+			used = usedTypes(idl, used, fn.Returns.Type)
+			for _, p := range fn.Params {
+				used = usedTypes(idl, used, p.Type)
+			}
+		}
+	} else if fn, ok := findFunction(idl, name); ok {
+		iface, _ := idl.Interface(name[:strings.Index(name, ".")])
+		writeInterface(w, iface, fn.Name)
+		used = usedTypes(idl, used, fn.Returns.Type)
+		for _, p := range fn.Params {
+			used = usedTypes(idl, used, p.Type)
+		}
+	} else if kind := idl.TypeKind(name); kind == barrister.KindStruct || kind == barrister.KindEnum {
+		used = usedTypes(idl, used, name)
+		wroteIface = false
+	} else {
+		return fmt.Errorf("IDL has no interface, method, struct or enum named %s", name)
+	}
+
+	for i, t := range used {
+		if i > 0 || wroteIface {
+			fmt.Fprintln(w)
+		}
+		writeType(w, idl, t)
+	}
+	return nil
+}
+
+// usedTypes appends the struct or enum typeName, and the structs and enums
+// it uses, to used if they are not already in it
+func usedTypes(idl *barrister.Idl, used []string, typeName string) []string {
+	kind := idl.TypeKind(typeName)
+	if kind != barrister.KindStruct && kind != barrister.KindEnum {
+		return used
+	}
+	for _, t := range used {
+		if t == typeName {
+			return used
+		}
+	}
+	used = append(used, typeName)
+
+	if s, ok := idl.Struct(typeName); ok {
+		if s.Extends != "" {
+			used = usedTypes(idl, used, s.Extends)
+		}
+		for _, f := range s.Fields {
+			used = usedTypes(idl, used, f.Type)
+		}
+	}
+	return used
+}
+
+// writeInterface writes iface, or only its function fnName if it is not empty
+func writeInterface(w io.Writer, iface barrister.Interface, fnName string) {
+	writeComment(w, "", iface.Comment)
+	fmt.Fprintf(w, "interface %s {\n", iface.Name)
+	first := true
+	for _, fn := range iface.Functions {
+		if fnName != "" && fn.Name != fnName {
+			continue
+		}
+		if !first {
+			fmt.Fprintln(w)
+		}
+		first = false
+
+		params := make([]string, len(fn.Params))
+		for i, p := range fn.Params {
+			params[i] = p.Name + " " + fieldType(p)
+		}
+		writeComment(w, "    ", fn.Comment)
+		fmt.Fprintf(w, "    %s(%s) %s%s\n", fn.Name, strings.Join(params, ", "), fieldType(fn.Returns), optional(fn.Returns))
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// writeType writes the struct or enum typeName.  Structs include the fields
+// inherited from the structs they extend.
+func writeType(w io.Writer, idl *barrister.Idl, typeName string) {
+	if s, ok := idl.Struct(typeName); ok {
+		writeComment(w, "", s.Comment)
+		if s.Extends != "" {
+			fmt.Fprintf(w, "struct %s extends %s {\n", s.Name, s.Extends)
+		} else {
+			fmt.Fprintf(w, "struct %s {\n", s.Name)
+		}
+		for _, f := range s.AllFields() {
+			writeComment(w, "    ", f.Comment)
+			fmt.Fprintf(w, "    %s %s%s\n", f.Name, fieldType(f), optional(f))
+		}
+		fmt.Fprintln(w, "}")
+		return
+	}
+
+	e, _ := idl.Enum(typeName)
+	writeComment(w, "", e.Comment)
+	fmt.Fprintf(w, "enum %s {\n", e.Name)
+	for _, v := range e.Values {
+		writeComment(w, "    ", v.Comment)
+		fmt.Fprintf(w, "    %s\n", v.Value)
+	}
+	fmt.Fprintln(w, "}")
+}
+
+func writeComment(w io.Writer, indent string, comment string) {
+	comment = strings.TrimRight(comment, "\n")
+	if comment == "" {
+		return
+	}
+	for _, ln := range strings.Split(comment, "\n") {
+		if ln == "" {
+			fmt.Fprintf(w, "%s//\n", indent)
+		} else {
+			fmt.Fprintf(w, "%s// %s\n", indent, ln)
+		}
+	}
+}
+
+func fieldType(f barrister.Field) string {
+	if f.IsArray {
+		return "[]" + f.Type
+	}
+	return f.Type
+}
+
+func optional(f barrister.Field) string {
+	if f.Optional {
+		return " [optional]"
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/coopernurse/barrister-go"
+	"strings"
+)
+
+// parseParams converts the args of the call command to the params of fn.
+// Each arg is the JSON value of a param.  String and enum params may also be
+// given as the string itself, without JSON quotes.  A struct param may instead
+// be given as consecutive key=value args, see parseFields.
+//
+// Params are not checked here, barrister.Invoke checks them against the IDL.
+func parseParams(idl *barrister.Idl, fn barrister.Function, args []string) ([]interface{}, error) {
+	params := []interface{}{}
+	for len(args) > 0 {
+		if len(params) >= len(fn.Params) {
+			// let Invoke report the number of params
+			params = append(params, args[0])
+			args = args[1:]
+			continue
+		}
+
+		p := fn.Params[len(params)]
+		if s, ok := idl.Struct(p.Type); ok && !p.IsArray && fieldArg(s, args[0]) {
+			m, n, err := parseFields(idl, s, args)
+			if err != nil {
+				return nil, fmt.Errorf("param %s: %s", p.Name, err)
+			}
+			params = append(params, m)
+			args = args[n:]
+			continue
+		}
+
+		v, err := parseValue(idl, p, args[0])
+		if err != nil {
+			return nil, fmt.Errorf("param %s: %s", p.Name, err)
+		}
+		params = append(params, v)
+		args = args[1:]
+	}
+	return params, nil
+}
+
+// parseValue converts arg to a value of the type of field f
+func parseValue(idl *barrister.Idl, f barrister.Field, arg string) (interface{}, error) {
+	isString := f.Type == "string" || idl.TypeKind(f.Type) == barrister.KindEnum
+	if isString && !f.IsArray && arg != "null" && !strings.HasPrefix(arg, `"`) {
+		return arg, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(arg)))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	if err == nil && dec.More() {
+		err = fmt.Errorf("unexpected data after JSON value")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON %s: %s", arg, err)
+	}
+	return v, nil
+}
+
+// parseFields converts the key=value args at the start of args to a struct
+// s, and returns it with the number of args used.  Keys are field names, or
+// paths to the fields of nested structs, e.g. "address.city".  Values are
+// converted with parseValue.  The struct ends at the first arg that is not a
+// field of s, or that sets a field again, which starts the next param.
+func parseFields(idl *barrister.Idl, s *barrister.Struct, args []string) (map[string]interface{}, int, error) {
+	m := map[string]interface{}{}
+	n := 0
+	for _, arg := range args {
+		if !fieldArg(s, arg) {
+			break
+		}
+		pos := strings.Index(arg, "=")
+		path := strings.Split(arg[:pos], ".")
+		if isSet(m, path) {
+			break
+		}
+		err := setField(idl, s, m, path, arg[pos+1:])
+		if err != nil {
+			return nil, 0, err
+		}
+		n++
+	}
+	return m, n, nil
+}
+
+// fieldArg returns true if arg is a key=value arg for a field of s
+func fieldArg(s *barrister.Struct, arg string) bool {
+	pos := strings.Index(arg, "=")
+	if pos < 1 {
+		return false
+	}
+	_, ok := findField(s, strings.SplitN(arg[:pos], ".", 2)[0])
+	return ok
+}
+
+func findField(s *barrister.Struct, name string) (barrister.Field, bool) {
+	for _, f := range s.AllFields() {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return barrister.Field{}, false
+}
+
+// setField sets the field at path in m, the JSON object of a struct s
+func setField(idl *barrister.Idl, s *barrister.Struct, m map[string]interface{}, path []string, value string) error {
+	f, ok := findField(s, path[0])
+	if !ok {
+		return fmt.Errorf("%s has no field %s", s.Name, path[0])
+	}
+
+	if len(path) == 1 {
+		v, err := parseValue(idl, f, value)
+		if err != nil {
+			return fmt.Errorf("%s: %s", f.Name, err)
+		}
+		m[f.Name] = v
+		return nil
+	}
+
+	nested, ok := idl.Struct(f.Type)
+	if !ok || f.IsArray {
+		return fmt.Errorf("%s.%s is not a struct", s.Name, f.Name)
+	}
+	sub, ok := m[f.Name].(map[string]interface{})
+	if !ok {
+		sub = map[string]interface{}{}
+		m[f.Name] = sub
+	}
+	err := setField(idl, nested, sub, path[1:], value)
+	if err != nil {
+		return fmt.Errorf("%s: %s", f.Name, err)
+	}
+	return nil
+}
+
+// isSet returns true if m has a value at path
+func isSet(m map[string]interface{}, path []string) bool {
+	v, ok := m[path[0]]
+	if !ok || len(path) == 1 {
+		return ok
+	}
+	sub, isMap := v.(map[string]interface{})
+	return !isMap || isSet(sub, path[1:])
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coopernurse/barrister-go"
+)
+
+var testIdl = barrister.MustParseIdl("users.idl", []byte(`
+enum Role {
+	admin
+	guest
+}
+
+struct Address {
+	city string
+	zip  string [optional]
+}
+
+struct Base {
+	id string
+}
+
+// A user
+struct User extends Base {
+	// full name
+	name    string
+	age     int
+	address Address [optional]
+	tags    []string
+}
+
+// Manages users
+interface UserService {
+	// saves a user
+	save(u User, role Role, note string) string
+	find(name string) User [optional]
+	link(a User, b User) bool
+}
+`))
+
+func TestParseParams(t *testing.T) {
+	for i, c := range []struct {
+		method   string
+		args     []string
+		expected []interface{}
+	}{
+		{"UserService.save", []string{"id=u1", "name=Bob", "age=3", "address.city=Paris", `tags=["a"]`, "admin", "hi there"}, []interface{}{
+			map[string]interface{}{"id": "u1", "name": "Bob", "age": json.Number("3"),
+				"address": map[string]interface{}{"city": "Paris"}, "tags": []interface{}{"a"}},
+			"admin", "hi there"}},
+		{"UserService.save", []string{`{"name": "Al", "age": 12345678901234567}`, `"guest"`, "123"}, []interface{}{
+			map[string]interface{}{"name": "Al", "age": json.Number("12345678901234567")}, "guest", "123"}},
+		{"UserService.find", []string{"null"}, []interface{}{nil}},
+		{"UserService.find", []string{"a=b", "extra"}, []interface{}{"a=b", "extra"}},
+		{"UserService.link", []string{"name=a", "name=b"}, []interface{}{
+			map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"}}},
+	} {
+		fn, ok := findFunction(testIdl, c.method)
+		if !ok {
+			t.Fatalf("%d: no function %s", i, c.method)
+		}
+		params, err := parseParams(testIdl, fn, c.args)
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+		} else if !reflect.DeepEqual(params, c.expected) {
+			t.Errorf("%d: expected %#v, got %#v", i, c.expected, params)
+		}
+	}
+
+	fn, _ := findFunction(testIdl, "UserService.save")
+	for args, expected := range map[string]string{
+		"age=x":           "param u: age: invalid JSON x",
+		"address.zip.x=1": "param u: address: Address.zip is not a struct",
+		"address.nope=1":  "param u: address: Address has no field nope",
+		"[1]":             "",
+	} {
+		_, err := parseParams(testIdl, fn, strings.Fields(args))
+		if expected == "" && err != nil {
+			t.Errorf("Unexpected error for %s: %s", args, err)
+		} else if expected != "" && (err == nil || !strings.HasPrefix(err.Error(), expected)) {
+			t.Errorf("Expected error %q for %s, got: %v", expected, args, err)
+		}
+	}
+}
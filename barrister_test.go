@@ -1,6 +1,7 @@
 package barrister
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -20,6 +21,9 @@ var enumField = &Field{Type: "StringAlias", Optional: false, IsArray: false}
 var arrField = &Field{Type: "float", Optional: false, IsArray: true}
 var optionalArrField = &Field{Type: "string", Optional: true, IsArray: true}
 
+var intField = &Field{Type: "int", Optional: false, IsArray: false}
+var floatField = &Field{Type: "float", Optional: false, IsArray: false}
+
 var noNestStruct = &Struct{Name: "NoNesting", Fields: []Field{
 	Field{Name: "a", Type: "string", Optional: true, IsArray: false},
 	Field{Name: "b", Type: "int", Optional: true, IsArray: false},
@@ -57,6 +61,23 @@ type Nested struct {
 	Nest NoNesting
 }
 
+type TaggedBase struct {
+	Text string `json:"a"`
+}
+
+// TaggedNesting holds the NoNesting fields under names unrelated to the IDL
+type TaggedNesting struct {
+	TaggedBase
+	Num    int64    `json:"b,omitempty"`
+	Ignore float64  `json:"-"`
+	C      float64  `json:"C"`
+	Flag   bool     `json:"d"`
+	List   []string `json:"E"`
+}
+
+// Cents is converted from IDL floats by a Converter registered in TestConvert
+type Cents int64
+
 //////////////////////////////////////
 
 func createTestIdl() *Idl {
@@ -111,6 +132,14 @@ func TestParseStuff(t *testing.T) {
 
 func TestConvert(t *testing.T) {
 	idl := createTestIdl()
+	RegisterConverter(reflect.TypeOf(Cents(0)), func(actual interface{}) (interface{}, error) {
+		f, ok := actual.(float64)
+		if !ok {
+			return nil, fmt.Errorf("expected a number")
+		}
+		return Cents(f * 100), nil
+	})
+	when := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
 
 	cases := []ConvertTest{
 		ConvertTest{"hi", "hi", strField, true},
@@ -127,6 +156,17 @@ func TestConvert(t *testing.T) {
 		ConvertTest{NoNesting{C: 2.8, D: false}, map[string]interface{}{"C": 2.8, "D": false}, noNestField, true},
 		ConvertTest{NoNesting{E: []string{"a", "b"}}, map[string]interface{}{"E": []string{"a", "b"}}, noNestField, true},
 		ConvertTest{Nested{Name: "hi", Nest: NoNesting{B: 30}}, map[string]interface{}{"name": "hi", "Nest": map[string]interface{}{"b": 30.0}}, nestField, true},
+		ConvertTest{TaggedNesting{TaggedBase: TaggedBase{"hi"}, Num: 30, Flag: true, List: []string{"x"}},
+			map[string]interface{}{"a": "hi", "b": 30, "d": true, "E": []string{"x"}}, noNestField, true},
+		ConvertTest{int32(30), 30, intField, true},
+		ConvertTest{int32(30), float64(30), intField, true},
+		ConvertTest{int8(0), 300, intField, false},
+		ConvertTest{int16(0), 1.5, intField, false},
+		ConvertTest{float32(1.5), 1.5, floatField, true},
+		ConvertTest{when, "2024-05-01T12:00:00Z", strField, true},
+		ConvertTest{time.Time{}, "May 1st", strField, false},
+		ConvertTest{Cents(1250), 12.5, floatField, true},
+		ConvertTest{Cents(0), "12.50", floatField, false},
 	}
 
 	for x, test := range cases {
@@ -156,6 +196,23 @@ func TestConvert(t *testing.T) {
 	}
 }
 
+func TestFieldGoName(t *testing.T) {
+	for _, c := range [][]string{
+		{"to_repeat", "ToRepeat"},
+		{"force_uppercase", "ForceUppercase"},
+		{"personId", "PersonID"},
+		{"user_id", "UserID"},
+		{"homeURL", "HomeURL"},
+		{"url", "URL"},
+		{"Name", "Name"},
+		{"_", "_"},
+		{"__private_key", "PrivateKey"},
+		{"utf8_name", "UTF8Name"},
+	} {
+		Equals(t, fieldGoName(c[0]), c[1])
+	}
+}
+
 func TestHttpTransport_Send_DefaultHTTPClient(t *testing.T) {
 	data := []byte("test")
 
@@ -301,3 +358,127 @@ func BenchmarkConvertStruct(b *testing.B) {
 		}
 	}
 }
+
+func TestCallIntoPreservesInt64(t *testing.T) {
+	svr := NewJSONServer(parseTestIdl(), true)
+	svr.AddDispatcher("A", AImpl{}, aDispatcher{})
+	client := NewRemoteClient(serverTransport{&svr}, false)
+
+	var sum int64
+	err := CallInto(context.Background(), client, svr.idl, "A.add", &sum, int64(1<<53), int64(1))
+	Equals(t, err, nil)
+	Equals(t, sum, int64(1<<53+1))
+
+	// the generic path rounds through float64
+	res, err := client.Call("A.add", int64(1<<53), int64(1))
+	Equals(t, err, nil)
+	NotEquals(t, int64(res.(float64)), int64(1<<53+1))
+}
+
+func TestCallInto(t *testing.T) {
+	svr := NewJSONServer(parseTestIdl(), true)
+	svr.AddHandler("A", AImpl{})
+	svr.AddHandler("B", BImpl{})
+	remote := NewRemoteClient(serverTransport{&svr}, false)
+
+	// plainClient hides RawClient, so CallInto falls back to Convert
+	type plainClient struct{ Client }
+
+	for _, client := range []Client{remote, plainClient{remote}} {
+		var id string
+		person := Person{PersonId: "p1", FirstName: "a", LastName: "b"}
+		err := CallInto(context.Background(), client, svr.idl, "A.putPerson", &id, person)
+		Equals(t, err, nil)
+		Equals(t, id, "p1")
+
+		s := "x"
+		err = CallInto(context.Background(), client, svr.idl, "B.echo", &s, "return-null")
+		Equals(t, err, nil)
+		Equals(t, s, "")
+
+		var sum int64
+		err = CallInto(context.Background(), client, svr.idl, "A.add", &sum, 1, "x")
+		NotEquals(t, err, nil)
+	}
+}
+
+// cannedTransport returns the same response to every request
+type cannedTransport string
+
+func (t cannedTransport) Send(in []byte) ([]byte, error) {
+	return []byte(t), nil
+}
+
+func TestCallIntoChecksRawResult(t *testing.T) {
+	idl := parseTestIdl()
+	for _, result := range []string{`{}`, `{"hi":null}`, `{"hi":5}`, `"hi"`} {
+		resp := `{"jsonrpc":"2.0","id":"1","result":` + result + `}`
+		client := NewRemoteClient(cannedTransport(resp), false)
+
+		var hi HiResponse
+		err := CallInto(context.Background(), client, idl, "A.say_hi", &hi)
+		NotEquals(t, err, nil)
+		Equals(t, err.(*JsonRpcError).Code, -32000)
+	}
+
+	client := NewRemoteClient(cannedTransport(`{"jsonrpc":"2.0","id":"1","result":{"hi":"there"}}`), false)
+	var hi HiResponse
+	err := CallInto(context.Background(), client, idl, "A.say_hi", &hi)
+	Equals(t, err, nil)
+	Equals(t, hi.Hi, "there")
+}
+
+// Money is converted from IDL floats by a Converter, in
+// TestConverterWithProxyAndDispatcher
+type Money struct {
+	Cents int64
+}
+
+// moneyDispatcher decodes the A.sqrt param as Money, and returns its cents
+type moneyDispatcher struct{}
+
+func (moneyDispatcher) Decode(_idl *Idl, _method string, _params []interface{}) (DispatchFunc, error) {
+	var a Money
+	if _err := DecodeParam(_idl, _method, _params, 0, &a); _err != nil {
+		return nil, _err
+	}
+	return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+		return float64(a.Cents), nil
+	}, nil
+}
+
+func TestConverterWithProxyAndDispatcher(t *testing.T) {
+	RegisterConverter(reflect.TypeOf(Money{}), func(actual interface{}) (interface{}, error) {
+		f, ok := actual.(float64)
+		if !ok {
+			return nil, fmt.Errorf("expected a number")
+		}
+		return Money{Cents: int64(f * 100)}, nil
+	})
+	idl := parseTestIdl()
+
+	// proxy result
+	svr := NewJSONServer(idl, false)
+	svr.AddHandler("A", AImpl{})
+	client := NewRemoteClient(&InMemoryTransport{Server: &svr}, false)
+	var m Money
+	err := CallInto(context.Background(), client, idl, "A.sqrt", &m, float64(6.25))
+	Equals(t, err, nil)
+	Equals(t, m, Money{250})
+
+	var pm *Money
+	err = CallInto(context.Background(), client, idl, "A.sqrt", &pm, float64(6.25))
+	Equals(t, err, nil)
+	Equals(t, *pm, Money{250})
+
+	// dispatcher param
+	dispatched := NewJSONServer(idl, false)
+	dispatched.AddDispatcher("A", AImpl{}, moneyDispatcher{})
+	Equals(t, invokeJson(t, &dispatched, `{"jsonrpc":"2.0","id":"1","method":"A.sqrt","params":[2.5]}`),
+		`{"jsonrpc":"2.0","id":"1","result":250}`)
+
+	// the value is still checked against the IDL
+	resp := JsonRpcResponse{}
+	json.Unmarshal([]byte(invokeJson(t, &dispatched, `{"jsonrpc":"2.0","id":"1","method":"A.sqrt","params":["2.5"]}`)), &resp)
+	Equals(t, resp.Error.Code, -32602)
+}
@@ -0,0 +1,158 @@
+package barrister
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"sort"
+	"unicode/utf16"
+)
+
+// ChecksumError is returned by VerifyChecksum when the checksum in the IDL
+// meta element does not match the IDL contents
+type ChecksumError struct {
+	// Checksum recorded in the IDL meta element
+	Expected string
+
+	// Checksum computed from the IDL elements
+	Actual string
+}
+
+func (e *ChecksumError) Error() string {
+	if e.Expected == "" {
+		return fmt.Sprintf("barrister: IDL has no checksum, computed checksum is %s", e.Actual)
+	}
+	return fmt.Sprintf("barrister: IDL checksum %s does not match computed checksum %s", e.Expected, e.Actual)
+}
+
+// ComputeChecksum returns the structural checksum of the IDL, which
+// ParseIdl writes to Meta.Checksum.
+//
+// The checksum ignores comments and the order of elements, struct fields,
+// functions and enum values, but changes when types, param order or enum
+// values change.  Each struct, enum and interface is reduced to a tab
+// separated signature, the signatures are sorted and encoded as a JSON array,
+// and the MD5 of that array is returned as a hex string.
+//
+// The checksums written by the Python translator are not reproduced yet, so
+// IDL JSON generated by the translator fails VerifyChecksum.
+func (idl *Idl) ComputeChecksum() string {
+	return elemsChecksum(idl.elems)
+}
+
+// VerifyChecksum returns a *ChecksumError if Meta.Checksum does not match
+// ComputeChecksum, which typically means the IDL JSON was edited by hand
+// after it was generated.
+func (idl *Idl) VerifyChecksum() error {
+	actual := idl.ComputeChecksum()
+	if idl.Meta.Checksum != actual {
+		return &ChecksumError{Expected: idl.Meta.Checksum, Actual: actual}
+	}
+	return nil
+}
+
+func elemsChecksum(elems []IdlJsonElem) string {
+	sigs := []string{}
+	for _, el := range elems {
+		sig := elemSignature(el)
+		if sig != "" {
+			sigs = append(sigs, sig)
+		}
+	}
+	sort.Strings(sigs)
+
+	b := &bytes.Buffer{}
+	b.WriteString("[")
+	for i, sig := range sigs {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		writePyJsonString(b, sig)
+	}
+	b.WriteString("]")
+
+	return fmt.Sprintf("%x", md5.Sum(b.Bytes()))
+}
+
+// elemSignature returns the checksum signature of a struct, enum or
+// interface element, or an empty string for other element types
+func elemSignature(el IdlJsonElem) string {
+	switch el.Type {
+	case "struct":
+		sorted := append([]Field(nil), el.Fields...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+		fields := ""
+		for _, f := range sorted {
+			fields += fmt.Sprintf("\t%s\t%s\t%s\t%s", f.Name, f.Type, pyBool(f.IsArray), pyBool(f.Optional))
+		}
+		return fmt.Sprintf("struct\t%s\t%s\t%s", el.Name, el.Extends, fields)
+	case "enum":
+		vals := make([]string, len(el.Values))
+		for i, v := range el.Values {
+			vals[i] = v.Value
+		}
+		sort.Strings(vals)
+		sig := "enum\t" + el.Name
+		for _, v := range vals {
+			sig += "\t" + v
+		}
+		return sig
+	case "interface":
+		funcs := append([]Function(nil), el.Functions...)
+		sort.SliceStable(funcs, func(i, j int) bool { return funcs[i].Name < funcs[j].Name })
+		sig := "interface\t" + el.Name
+		for _, fn := range funcs {
+			sig += "[" + fn.Name
+			for _, p := range fn.Params {
+				sig += fmt.Sprintf("\t%s\t%s", p.Type, pyBool(p.IsArray))
+			}
+			r := fn.Returns
+			sig += fmt.Sprintf("\t%s\t%s\t%s]", r.Type, pyBool(r.IsArray), pyBool(r.Optional))
+		}
+		return sig
+	}
+	return ""
+}
+
+// pyBool formats b the way Python's str() does
+func pyBool(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
+
+// writePyJsonString writes s as a JSON string escaped the way Python's
+// json.dumps does by default (ensure_ascii=True).  encoding/json differs
+// in escaping <, > and & and in how non-ASCII runes are written.
+func writePyJsonString(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if r < 0x20 || (r > 0x7f && r <= 0xffff) {
+				fmt.Fprintf(b, `\u%04x`, r)
+			} else if r > 0xffff {
+				r1, r2 := utf16.EncodeRune(r)
+				fmt.Fprintf(b, `\u%04x\u%04x`, r1, r2)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
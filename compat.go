@@ -0,0 +1,387 @@
+package barrister
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CompatChangeKind identifies the kind of difference found between two IDLs
+type CompatChangeKind string
+
+const (
+	StructAdded        CompatChangeKind = "struct-added"
+	StructRemoved      CompatChangeKind = "struct-removed"
+	ExtendsChanged     CompatChangeKind = "extends-changed"
+	FieldAdded         CompatChangeKind = "field-added"
+	FieldRemoved       CompatChangeKind = "field-removed"
+	FieldTypeChanged   CompatChangeKind = "field-type-changed"
+	FieldMadeOptional  CompatChangeKind = "field-made-optional"
+	FieldMadeRequired  CompatChangeKind = "field-made-required"
+	EnumAdded          CompatChangeKind = "enum-added"
+	EnumRemoved        CompatChangeKind = "enum-removed"
+	EnumValueAdded     CompatChangeKind = "enum-value-added"
+	EnumValueRemoved   CompatChangeKind = "enum-value-removed"
+	ElemKindChanged    CompatChangeKind = "kind-changed"
+	InterfaceAdded     CompatChangeKind = "interface-added"
+	InterfaceRemoved   CompatChangeKind = "interface-removed"
+	FunctionAdded      CompatChangeKind = "function-added"
+	FunctionRemoved    CompatChangeKind = "function-removed"
+	ParamAdded         CompatChangeKind = "param-added"
+	ParamRemoved       CompatChangeKind = "param-removed"
+	ParamRenamed       CompatChangeKind = "param-renamed"
+	ParamTypeChanged   CompatChangeKind = "param-type-changed"
+	ReturnTypeChanged  CompatChangeKind = "return-type-changed"
+	ReturnMadeOptional CompatChangeKind = "return-made-optional"
+	ReturnMadeRequired CompatChangeKind = "return-made-required"
+)
+
+// CompatChange is a single difference between an old and a new version of an IDL.
+//
+// Compatibility is judged on the wire:
+//
+// BreaksClients is true if clients built against the old IDL may fail when
+// calling a server that implements the new IDL.
+//
+// BreaksServers is true if clients built against the new IDL may fail when
+// calling a server that still implements the old IDL.
+type CompatChange struct {
+	Kind CompatChangeKind
+
+	// Where the change occurred, e.g. "struct Person field email"
+	Location string
+
+	// Human readable description of the change
+	Detail string
+
+	BreaksClients bool
+	BreaksServers bool
+}
+
+// Breaking returns true if the change breaks clients or servers
+func (c CompatChange) Breaking() bool {
+	return c.BreaksClients || c.BreaksServers
+}
+
+func (c CompatChange) String() string {
+	return fmt.Sprintf("%s: %s: %s", c.Location, c.Kind, c.Detail)
+}
+
+// CompareIdl compares two versions of an IDL and returns every change
+// that affects the wire contract.  Comments are ignored.
+//
+// Changes to structs and enums are classified based on how the type is used:
+// types reachable from function params are sent by clients, types reachable
+// from return values are sent by servers.  Types used in neither are reported
+// as compatible.
+func CompareIdl(oldIdl, newIdl *Idl) []CompatChange {
+	c := &idlComparer{
+		old:    oldIdl,
+		new:    newIdl,
+		input:  map[string]bool{},
+		output: map[string]bool{},
+	}
+	for _, idl := range []*Idl{oldIdl, newIdl} {
+		for _, funcs := range idl.interfaces {
+			for _, fn := range funcs {
+				for _, p := range fn.Params {
+					markReachable(idl, p.Type, c.input)
+				}
+				markReachable(idl, fn.Returns.Type, c.output)
+			}
+		}
+	}
+
+	c.compareStructs()
+	c.compareEnums()
+	c.compareInterfaces()
+	return c.changes
+}
+
+// markReachable adds typeName and all struct and enum types reachable
+// from its fields to seen
+func markReachable(idl *Idl, typeName string, seen map[string]bool) {
+	if seen[typeName] {
+		return
+	}
+	seen[typeName] = true
+	s, ok := idl.structs[typeName]
+	if ok {
+		for _, f := range s.allFields {
+			markReachable(idl, f.Type, seen)
+		}
+	}
+}
+
+type idlComparer struct {
+	old *Idl
+	new *Idl
+
+	// names of types sent as params (input) and returned (output)
+	input  map[string]bool
+	output map[string]bool
+
+	changes []CompatChange
+}
+
+// breaks describes which side a change breaks when the changed type is
+// sent as a param (in) or returned (out)
+type breaks struct {
+	inClients, inServers   bool
+	outClients, outServers bool
+}
+
+var (
+	// change makes a value more restricted than before, e.g. a new required field
+	breaksRestrict = breaks{inClients: true, outServers: true}
+
+	// change allows values that were not allowed before, e.g. a new enum value
+	breaksRelax = breaks{inServers: true, outClients: true}
+
+	breaksBoth = breaks{true, true, true, true}
+	breaksNone = breaks{}
+)
+
+func (c *idlComparer) add(kind CompatChangeKind, loc string, typeName string, b breaks, detail string, args ...interface{}) {
+	in := c.input[typeName]
+	out := c.output[typeName]
+	c.changes = append(c.changes, CompatChange{
+		Kind:          kind,
+		Location:      loc,
+		Detail:        fmt.Sprintf(detail, args...),
+		BreaksClients: (in && b.inClients) || (out && b.outClients),
+		BreaksServers: (in && b.inServers) || (out && b.outServers),
+	})
+}
+
+func (c *idlComparer) addFunc(kind CompatChangeKind, loc string, breaksClients, breaksServers bool, detail string, args ...interface{}) {
+	c.changes = append(c.changes, CompatChange{
+		Kind:          kind,
+		Location:      loc,
+		Detail:        fmt.Sprintf(detail, args...),
+		BreaksClients: breaksClients,
+		BreaksServers: breaksServers,
+	})
+}
+
+func describeType(f Field) string {
+	if f.IsArray {
+		return "[]" + f.Type
+	}
+	return f.Type
+}
+
+func sortedStructNames(m map[string]*Struct) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedEnumNames(m map[string][]EnumValue) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *idlComparer) compareStructs() {
+	for _, name := range sortedStructNames(c.old.structs) {
+		loc := "struct " + name
+		oldStruct := c.old.structs[name]
+		newStruct, ok := c.new.structs[name]
+		if !ok {
+			if _, isEnum := c.new.enums[name]; isEnum {
+				c.add(ElemKindChanged, loc, name, breaksBoth, "struct changed to enum")
+			} else {
+				c.add(StructRemoved, loc, name, breaksBoth, "struct removed")
+			}
+			continue
+		}
+
+		if oldStruct.Extends != newStruct.Extends {
+			c.add(ExtendsChanged, loc, name, breaksNone, "extends changed from '%s' to '%s'",
+				oldStruct.Extends, newStruct.Extends)
+		}
+		c.compareFields(name, oldStruct.allFields, newStruct.allFields)
+	}
+
+	for _, name := range sortedStructNames(c.new.structs) {
+		_, inOld := c.old.structs[name]
+		_, wasEnum := c.old.enums[name]
+		if !inOld && !wasEnum {
+			c.add(StructAdded, "struct "+name, name, breaksNone, "struct added")
+		}
+	}
+}
+
+func (c *idlComparer) compareFields(structName string, oldFields, newFields []Field) {
+	newByName := map[string]Field{}
+	for _, f := range newFields {
+		newByName[f.Name] = f
+	}
+	oldByName := map[string]Field{}
+	for _, f := range oldFields {
+		oldByName[f.Name] = f
+	}
+
+	for _, oldField := range oldFields {
+		loc := fmt.Sprintf("struct %s field %s", structName, oldField.Name)
+		newField, ok := newByName[oldField.Name]
+		if !ok {
+			if oldField.Optional {
+				c.add(FieldRemoved, loc, structName, breaksNone, "optional field removed")
+			} else {
+				c.add(FieldRemoved, loc, structName, breaksRelax, "required field removed")
+			}
+			continue
+		}
+
+		if describeType(oldField) != describeType(newField) {
+			c.add(FieldTypeChanged, loc, structName, breaksBoth, "type changed from %s to %s",
+				describeType(oldField), describeType(newField))
+		}
+		if oldField.Optional && !newField.Optional {
+			c.add(FieldMadeRequired, loc, structName, breaksRestrict, "optional field made required")
+		} else if !oldField.Optional && newField.Optional {
+			c.add(FieldMadeOptional, loc, structName, breaksRelax, "required field made optional")
+		}
+	}
+
+	for _, newField := range newFields {
+		if _, ok := oldByName[newField.Name]; ok {
+			continue
+		}
+		loc := fmt.Sprintf("struct %s field %s", structName, newField.Name)
+		if newField.Optional {
+			c.add(FieldAdded, loc, structName, breaksNone, "optional field added")
+		} else {
+			c.add(FieldAdded, loc, structName, breaksRestrict, "required field added")
+		}
+	}
+}
+
+func (c *idlComparer) compareEnums() {
+	for _, name := range sortedEnumNames(c.old.enums) {
+		loc := "enum " + name
+		oldVals := c.old.enums[name]
+		newVals, ok := c.new.enums[name]
+		if !ok {
+			if _, isStruct := c.new.structs[name]; isStruct {
+				c.add(ElemKindChanged, loc, name, breaksBoth, "enum changed to struct")
+			} else {
+				c.add(EnumRemoved, loc, name, breaksBoth, "enum removed")
+			}
+			continue
+		}
+
+		for _, v := range oldVals {
+			if !enumHasValue(newVals, v.Value) {
+				c.add(EnumValueRemoved, loc+" value "+v.Value, name, breaksRestrict, "value removed")
+			}
+		}
+		for _, v := range newVals {
+			if !enumHasValue(oldVals, v.Value) {
+				c.add(EnumValueAdded, loc+" value "+v.Value, name, breaksRelax, "value added")
+			}
+		}
+	}
+
+	for _, name := range sortedEnumNames(c.new.enums) {
+		_, inOld := c.old.enums[name]
+		_, wasStruct := c.old.structs[name]
+		if !inOld && !wasStruct {
+			c.add(EnumAdded, "enum "+name, name, breaksNone, "enum added")
+		}
+	}
+}
+
+func enumHasValue(vals []EnumValue, value string) bool {
+	for _, v := range vals {
+		if v.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *idlComparer) compareInterfaces() {
+	for _, name := range sortedKeys(c.old.interfaces) {
+		loc := "interface " + name
+		newFuncs, ok := c.new.interfaces[name]
+		if !ok {
+			c.addFunc(InterfaceRemoved, loc, true, false, "interface removed")
+			continue
+		}
+
+		for _, oldFn := range c.old.interfaces[name] {
+			fnLoc := fmt.Sprintf("%s function %s", loc, oldFn.Name)
+			newFn, ok := findFunction(newFuncs, oldFn.Name)
+			if !ok {
+				c.addFunc(FunctionRemoved, fnLoc, true, false, "function removed")
+				continue
+			}
+			c.compareFunction(fnLoc, oldFn, newFn)
+		}
+
+		for _, newFn := range newFuncs {
+			if _, ok := findFunction(c.old.interfaces[name], newFn.Name); !ok {
+				c.addFunc(FunctionAdded, fmt.Sprintf("%s function %s", loc, newFn.Name),
+					false, true, "function added")
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(c.new.interfaces) {
+		if _, ok := c.old.interfaces[name]; !ok {
+			c.addFunc(InterfaceAdded, "interface "+name, false, true, "interface added")
+		}
+	}
+}
+
+func findFunction(funcs []Function, name string) (Function, bool) {
+	for _, fn := range funcs {
+		if fn.Name == name {
+			return fn, true
+		}
+	}
+	return Function{}, false
+}
+
+func (c *idlComparer) compareFunction(loc string, oldFn, newFn Function) {
+	for x, oldParam := range oldFn.Params {
+		paramLoc := fmt.Sprintf("%s param[%d]", loc, x)
+		if x >= len(newFn.Params) {
+			c.addFunc(ParamRemoved, paramLoc, true, true, "param '%s' removed", oldParam.Name)
+			continue
+		}
+
+		newParam := newFn.Params[x]
+		if describeType(oldParam) != describeType(newParam) {
+			c.addFunc(ParamTypeChanged, paramLoc, true, true, "type changed from %s to %s",
+				describeType(oldParam), describeType(newParam))
+		}
+		if oldParam.Name != newParam.Name {
+			c.addFunc(ParamRenamed, paramLoc, false, false, "renamed from '%s' to '%s'",
+				oldParam.Name, newParam.Name)
+		}
+	}
+	for x := len(oldFn.Params); x < len(newFn.Params); x++ {
+		c.addFunc(ParamAdded, fmt.Sprintf("%s param[%d]", loc, x), true, true,
+			"param '%s' added", newFn.Params[x].Name)
+	}
+
+	retLoc := loc + " returns"
+	if describeType(oldFn.Returns) != describeType(newFn.Returns) {
+		c.addFunc(ReturnTypeChanged, retLoc, true, true, "type changed from %s to %s",
+			describeType(oldFn.Returns), describeType(newFn.Returns))
+	}
+	if oldFn.Returns.Optional && !newFn.Returns.Optional {
+		c.addFunc(ReturnMadeRequired, retLoc, false, true, "optional return value made required")
+	} else if !oldFn.Returns.Optional && newFn.Returns.Optional {
+		c.addFunc(ReturnMadeOptional, retLoc, true, false, "required return value made optional")
+	}
+}
@@ -0,0 +1,96 @@
+package barrister
+
+import (
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestCompareIdlIdentical(t *testing.T) {
+	Equals(t, len(CompareIdl(parseTestIdl(), parseTestIdl())), 0)
+}
+
+func TestCompareIdl(t *testing.T) {
+	oldIdl := MustParseIdl("old.idl", []byte(`
+enum Status { ok err }
+enum Color { red green }
+
+struct Request {
+    name  string
+    email string [optional]
+    age   int
+}
+
+struct Response {
+    status Status
+    note   string
+}
+
+struct Unused { a string }
+
+interface Svc {
+    // comments are ignored
+    call(req Request, color Color) Response
+    ping() string [optional]
+    gone() bool
+}
+`))
+	newIdl := MustParseIdl("new.idl", []byte(`
+enum Status { ok err unknown }
+enum Color { red }
+
+struct Request {
+    name  string
+    email string
+    age   float
+    phone string [optional]
+}
+
+struct Response {
+    status Status
+    code   int
+}
+
+struct Unused { a string b int }
+
+interface Svc {
+    call(request Request, color Color, extra int) Response
+    ping() string
+    added() bool
+}
+
+interface Other {
+    x() bool
+}
+`))
+
+	type result struct {
+		kind    CompatChangeKind
+		loc     string
+		clients bool
+		servers bool
+	}
+	expected := []result{
+		{FieldMadeRequired, "struct Request field email", true, false},
+		{FieldTypeChanged, "struct Request field age", true, true},
+		{FieldAdded, "struct Request field phone", false, false},
+		{FieldRemoved, "struct Response field note", true, false},
+		{FieldAdded, "struct Response field code", false, true},
+		{FieldAdded, "struct Unused field b", false, false},
+		{EnumValueRemoved, "enum Color value green", true, false},
+		{EnumValueAdded, "enum Status value unknown", true, false},
+		{ParamRenamed, "interface Svc function call param[0]", false, false},
+		{ParamAdded, "interface Svc function call param[2]", true, true},
+		{ReturnMadeRequired, "interface Svc function ping returns", false, true},
+		{FunctionRemoved, "interface Svc function gone", true, false},
+		{FunctionAdded, "interface Svc function added", false, true},
+		{InterfaceAdded, "interface Other", false, true},
+	}
+
+	changes := CompareIdl(oldIdl, newIdl)
+	actual := make([]result, len(changes))
+	for i, c := range changes {
+		actual[i] = result{c.Kind, c.Location, c.BreaksClients, c.BreaksServers}
+	}
+	DeepEquals(t, actual, expected)
+}
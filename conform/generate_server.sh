@@ -72,8 +72,8 @@ func (i AImpl'"$suffix"') Sqrt('"$context_arg"'a float64) (float64, error) {
 func (a AImpl'"$suffix"') Repeat('"$context_arg"'req1 RepeatRequest) (RepeatResponse, error) {
 	rr := RepeatResponse{inc.Response{"ok"}, req1.Count, []string{}}
 
-	s := req1.To_repeat
-	if req1.Force_uppercase {
+	s := req1.ToRepeat
+	if req1.ForceUppercase {
 		s = strings.ToUpper(s)
 	}
 	for i := int64(0); i < req1.Count; i++ {
@@ -104,7 +104,7 @@ func (a AImpl'"$suffix"') Repeat_num('"$context_arg"'num int64, count int64) ([]
 // we use this to test the '"'"'[optional]'"'"' enforcement, 
 // as we invoke it with a null email
 func (a AImpl'"$suffix"') PutPerson('"$context_arg"'p Person) (string, error) {
-	return p.PersonId, nil
+	return p.PersonID, nil
 }
 
 type BImpl'"$suffix"' struct{}
@@ -0,0 +1,794 @@
+// Code generated by idl2go from JSON generated by Barrister v0.1.2
+
+// Barrister conformance IDL
+//
+// The bits in here have silly names and the operations
+// are not intended to be useful.  The intent is to
+// exercise as much of the IDL grammar as possible
+package conform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/coopernurse/barrister-go"
+)
+
+const BarristerVersion string = "0.1.2"
+const BarristerChecksum string = "34f6238ed03c6319017382e0fdc638a7"
+const BarristerDateGenerated int64 = 1337654725230000000
+
+type Status string
+
+const (
+	StatusOk  Status = "ok"
+	StatusErr Status = "err"
+)
+
+// StatusValues returns all the Status values, in the order they are declared in the IDL
+func StatusValues() []Status {
+	return []Status{StatusOk, StatusErr}
+}
+
+// ParseStatus returns s as a Status, or a *barrister.ValidationError if it is not one of the values
+func ParseStatus(s string) (Status, error) {
+	_e := Status(s)
+	if _err := _e.Validate(); _err != nil {
+		return "", _err
+	}
+	return _e, nil
+}
+
+// IsValid returns true if _e is one of the Status values
+func (_e Status) IsValid() bool {
+	switch _e {
+	case StatusOk, StatusErr:
+		return true
+	}
+	return false
+}
+
+func (_e Status) String() string {
+	return string(_e)
+}
+
+// Validate returns a *barrister.ValidationError if _e is not one of the Status values
+func (_e Status) Validate() error {
+	if _e.IsValid() {
+		return nil
+	}
+	return &barrister.ValidationError{Path: "Status", Msg: "Value '" + string(_e) + "' not in enum values: 'ok', 'err'"}
+}
+
+// UnmarshalJSON implements json.Unmarshaler, and returns an error if the
+// value is not one of the Status values
+func (_e *Status) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var _s string
+	if _err := json.Unmarshal(b, &_s); _err != nil {
+		return _err
+	}
+	_v, _err := ParseStatus(_s)
+	if _err != nil {
+		return _err
+	}
+	*_e = _v
+	return nil
+}
+
+type MathOp string
+
+const (
+	MathOpAdd MathOp = "add"
+	// mult comment
+	MathOpMultiply MathOp = "multiply"
+)
+
+// MathOpValues returns all the MathOp values, in the order they are declared in the IDL
+func MathOpValues() []MathOp {
+	return []MathOp{MathOpAdd, MathOpMultiply}
+}
+
+// ParseMathOp returns s as a MathOp, or a *barrister.ValidationError if it is not one of the values
+func ParseMathOp(s string) (MathOp, error) {
+	_e := MathOp(s)
+	if _err := _e.Validate(); _err != nil {
+		return "", _err
+	}
+	return _e, nil
+}
+
+// IsValid returns true if _e is one of the MathOp values
+func (_e MathOp) IsValid() bool {
+	switch _e {
+	case MathOpAdd, MathOpMultiply:
+		return true
+	}
+	return false
+}
+
+func (_e MathOp) String() string {
+	return string(_e)
+}
+
+// Validate returns a *barrister.ValidationError if _e is not one of the MathOp values
+func (_e MathOp) Validate() error {
+	if _e.IsValid() {
+		return nil
+	}
+	return &barrister.ValidationError{Path: "MathOp", Msg: "Value '" + string(_e) + "' not in enum values: 'add', 'multiply'"}
+}
+
+// UnmarshalJSON implements json.Unmarshaler, and returns an error if the
+// value is not one of the MathOp values
+func (_e *MathOp) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var _s string
+	if _err := json.Unmarshal(b, &_s); _err != nil {
+		return _err
+	}
+	_v, _err := ParseMathOp(_s)
+	if _err != nil {
+		return _err
+	}
+	*_e = _v
+	return nil
+}
+
+type Response struct {
+	Status Status `json:"status"`
+}
+
+// Validate returns a *barrister.ValidationError if a required field is nil,
+// or if an enum or struct field, or element of an array field, is invalid
+func (_s Response) Validate() error {
+	if _err := _s.Status.Validate(); _err != nil {
+		return barrister.ValidationErrorAt("Response.status", _err)
+	}
+	return nil
+}
+
+// testing struct inheritance
+type RepeatResponse struct {
+	Response
+	Count int64    `json:"count"`
+	Items []string `json:"items"`
+}
+
+// Validate returns a *barrister.ValidationError if a required field is nil,
+// or if an enum or struct field, or element of an array field, is invalid
+func (_s RepeatResponse) Validate() error {
+	if _err := _s.Response.Validate(); _err != nil {
+		return barrister.ValidationErrorAt("RepeatResponse", _err)
+	}
+	if _s.Items == nil {
+		return &barrister.ValidationError{Path: "RepeatResponse.items", Msg: "required field is nil"}
+	}
+	return nil
+}
+
+type HiResponse struct {
+	Hi string `json:"hi"`
+}
+
+// Validate returns a *barrister.ValidationError if a required field is nil,
+// or if an enum or struct field, or element of an array field, is invalid
+func (_s HiResponse) Validate() error {
+	return nil
+}
+
+type RepeatRequest struct {
+	ToRepeat       string `json:"to_repeat"`
+	Count          int64  `json:"count"`
+	ForceUppercase bool   `json:"force_uppercase"`
+}
+
+// Validate returns a *barrister.ValidationError if a required field is nil,
+// or if an enum or struct field, or element of an array field, is invalid
+func (_s RepeatRequest) Validate() error {
+	return nil
+}
+
+type Person struct {
+	PersonID  string  `json:"personId"`
+	FirstName string  `json:"firstName"`
+	LastName  string  `json:"lastName"`
+	Email     *string `json:"email,omitempty"`
+}
+
+// Validate returns a *barrister.ValidationError if a required field is nil,
+// or if an enum or struct field, or element of an array field, is invalid
+func (_s Person) Validate() error {
+	return nil
+}
+
+type A interface {
+	// returns a+b
+	Add(a int64, b int64) (int64, error)
+	// performs the given operation against
+	// all the values in nums and returns the result
+	Calc(nums []float64, operation MathOp) (float64, error)
+	// returns the square root of a
+	Sqrt(a float64) (float64, error)
+	// Echos the req1.to_repeat string as a list,
+	// optionally forcing to_repeat to upper case
+	//
+	// RepeatResponse.items should be a list of strings
+	// whose length is equal to req1.count
+	Repeat(req1 RepeatRequest) (RepeatResponse, error)
+	// returns a result with:
+	//   hi="hi" and status="ok"
+	Say_hi() (HiResponse, error)
+	// returns num as an array repeated 'count' number of times
+	Repeat_num(num int64, count int64) ([]int64, error)
+	// simply returns p.personId
+	//
+	// we use this to test the '[optional]' enforcement,
+	// as we invoke it with a null email
+	PutPerson(p Person) (string, error)
+}
+
+func NewAProxy(c barrister.Client) A {
+	return AProxy{c, barrister.MustParseIdlJson([]byte(IdlJsonRaw))}
+}
+
+type AProxy struct {
+	client barrister.Client
+	idl    *barrister.Idl
+}
+
+// returns a+b
+func (_p AProxy) Add(a int64, b int64) (int64, error) {
+	var _res int64
+	_err := barrister.CallInto(context.Background(), _p.client, _p.idl, "A.add", &_res, a, b)
+	if _err != nil {
+		return int64(0), _err
+	}
+	return _res, nil
+}
+
+// performs the given operation against
+// all the values in nums and returns the result
+func (_p AProxy) Calc(nums []float64, operation MathOp) (float64, error) {
+	var _res float64
+	_err := barrister.CallInto(context.Background(), _p.client, _p.idl, "A.calc", &_res, nums, operation)
+	if _err != nil {
+		return float64(0), _err
+	}
+	return _res, nil
+}
+
+// returns the square root of a
+func (_p AProxy) Sqrt(a float64) (float64, error) {
+	var _res float64
+	_err := barrister.CallInto(context.Background(), _p.client, _p.idl, "A.sqrt", &_res, a)
+	if _err != nil {
+		return float64(0), _err
+	}
+	return _res, nil
+}
+
+// Echos the req1.to_repeat string as a list,
+// optionally forcing to_repeat to upper case
+//
+// RepeatResponse.items should be a list of strings
+// whose length is equal to req1.count
+func (_p AProxy) Repeat(req1 RepeatRequest) (RepeatResponse, error) {
+	var _res RepeatResponse
+	_err := barrister.CallInto(context.Background(), _p.client, _p.idl, "A.repeat", &_res, req1)
+	if _err != nil {
+		return RepeatResponse{}, _err
+	}
+	return _res, nil
+}
+
+// returns a result with:
+//
+//	hi="hi" and status="ok"
+func (_p AProxy) Say_hi() (HiResponse, error) {
+	var _res HiResponse
+	_err := barrister.CallInto(context.Background(), _p.client, _p.idl, "A.say_hi", &_res)
+	if _err != nil {
+		return HiResponse{}, _err
+	}
+	return _res, nil
+}
+
+// returns num as an array repeated 'count' number of times
+func (_p AProxy) Repeat_num(num int64, count int64) ([]int64, error) {
+	var _res []int64
+	_err := barrister.CallInto(context.Background(), _p.client, _p.idl, "A.repeat_num", &_res, num, count)
+	if _err != nil {
+		return []int64{}, _err
+	}
+	return _res, nil
+}
+
+// simply returns p.personId
+//
+// we use this to test the '[optional]' enforcement,
+// as we invoke it with a null email
+func (_p AProxy) PutPerson(p Person) (string, error) {
+	var _res string
+	_err := barrister.CallInto(context.Background(), _p.client, _p.idl, "A.putPerson", &_res, p)
+	if _err != nil {
+		return "", _err
+	}
+	return _res, nil
+}
+
+// ADispatcher invokes A handlers registered with barrister.Server.AddDispatcher
+type ADispatcher struct{}
+
+func (ADispatcher) Decode(_idl *barrister.Idl, _method string, _params []interface{}) (barrister.DispatchFunc, error) {
+	switch _method {
+	case "A.add":
+		var a int64
+		if _err := barrister.DecodeParam(_idl, _method, _params, 0, &a); _err != nil {
+			return nil, _err
+		}
+		var b int64
+		if _err := barrister.DecodeParam(_idl, _method, _params, 1, &b); _err != nil {
+			return nil, _err
+		}
+		return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+			_h, _ok := _handler.(A)
+			if !_ok {
+				return nil, &barrister.JsonRpcError{Code: -32603, Message: fmt.Sprintf("barrister: handler %T does not implement A", _handler)}
+			}
+			return _h.Add(a, b)
+		}, nil
+	case "A.calc":
+		var nums []float64
+		if _err := barrister.DecodeParam(_idl, _method, _params, 0, &nums); _err != nil {
+			return nil, _err
+		}
+		var operation MathOp
+		if _err := barrister.DecodeParam(_idl, _method, _params, 1, &operation); _err != nil {
+			return nil, _err
+		}
+		return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+			_h, _ok := _handler.(A)
+			if !_ok {
+				return nil, &barrister.JsonRpcError{Code: -32603, Message: fmt.Sprintf("barrister: handler %T does not implement A", _handler)}
+			}
+			return _h.Calc(nums, operation)
+		}, nil
+	case "A.sqrt":
+		var a float64
+		if _err := barrister.DecodeParam(_idl, _method, _params, 0, &a); _err != nil {
+			return nil, _err
+		}
+		return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+			_h, _ok := _handler.(A)
+			if !_ok {
+				return nil, &barrister.JsonRpcError{Code: -32603, Message: fmt.Sprintf("barrister: handler %T does not implement A", _handler)}
+			}
+			return _h.Sqrt(a)
+		}, nil
+	case "A.repeat":
+		var req1 RepeatRequest
+		if _err := barrister.DecodeParam(_idl, _method, _params, 0, &req1); _err != nil {
+			return nil, _err
+		}
+		return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+			_h, _ok := _handler.(A)
+			if !_ok {
+				return nil, &barrister.JsonRpcError{Code: -32603, Message: fmt.Sprintf("barrister: handler %T does not implement A", _handler)}
+			}
+			return _h.Repeat(req1)
+		}, nil
+	case "A.say_hi":
+		return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+			_h, _ok := _handler.(A)
+			if !_ok {
+				return nil, &barrister.JsonRpcError{Code: -32603, Message: fmt.Sprintf("barrister: handler %T does not implement A", _handler)}
+			}
+			return _h.Say_hi()
+		}, nil
+	case "A.repeat_num":
+		var num int64
+		if _err := barrister.DecodeParam(_idl, _method, _params, 0, &num); _err != nil {
+			return nil, _err
+		}
+		var count int64
+		if _err := barrister.DecodeParam(_idl, _method, _params, 1, &count); _err != nil {
+			return nil, _err
+		}
+		return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+			_h, _ok := _handler.(A)
+			if !_ok {
+				return nil, &barrister.JsonRpcError{Code: -32603, Message: fmt.Sprintf("barrister: handler %T does not implement A", _handler)}
+			}
+			return _h.Repeat_num(num, count)
+		}, nil
+	case "A.putPerson":
+		var p Person
+		if _err := barrister.DecodeParam(_idl, _method, _params, 0, &p); _err != nil {
+			return nil, _err
+		}
+		return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+			_h, _ok := _handler.(A)
+			if !_ok {
+				return nil, &barrister.JsonRpcError{Code: -32603, Message: fmt.Sprintf("barrister: handler %T does not implement A", _handler)}
+			}
+			return _h.PutPerson(p)
+		}, nil
+	}
+	return nil, &barrister.JsonRpcError{Code: -32601, Message: fmt.Sprintf("Unsupported method: %s", _method)}
+}
+
+// a second interface to prove that the server dispatcher
+// understands how to distinguish between interfaces in a contract
+type B interface {
+	// simply returns s
+	// if s == "return-null" then you should return a null
+	Echo(s string) (*string, error)
+}
+
+func NewBProxy(c barrister.Client) B {
+	return BProxy{c, barrister.MustParseIdlJson([]byte(IdlJsonRaw))}
+}
+
+type BProxy struct {
+	client barrister.Client
+	idl    *barrister.Idl
+}
+
+// simply returns s
+// if s == "return-null" then you should return a null
+func (_p BProxy) Echo(s string) (*string, error) {
+	var _res *string
+	_err := barrister.CallInto(context.Background(), _p.client, _p.idl, "B.echo", &_res, s)
+	if _err != nil {
+		return nil, _err
+	}
+	return _res, nil
+}
+
+// BDispatcher invokes B handlers registered with barrister.Server.AddDispatcher
+type BDispatcher struct{}
+
+func (BDispatcher) Decode(_idl *barrister.Idl, _method string, _params []interface{}) (barrister.DispatchFunc, error) {
+	switch _method {
+	case "B.echo":
+		var s string
+		if _err := barrister.DecodeParam(_idl, _method, _params, 0, &s); _err != nil {
+			return nil, _err
+		}
+		return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+			_h, _ok := _handler.(B)
+			if !_ok {
+				return nil, &barrister.JsonRpcError{Code: -32603, Message: fmt.Sprintf("barrister: handler %T does not implement B", _handler)}
+			}
+			return _h.Echo(s)
+		}, nil
+	}
+	return nil, &barrister.JsonRpcError{Code: -32601, Message: fmt.Sprintf("Unsupported method: %s", _method)}
+}
+
+func NewJSONServer(idl *barrister.Idl, forceASCII bool, a A, b B) barrister.Server {
+	return NewServer(idl, &barrister.JsonSerializer{forceASCII}, a, b)
+}
+
+func NewServer(idl *barrister.Idl, ser barrister.Serializer, a A, b B) barrister.Server {
+	_svr := barrister.NewServer(idl, ser)
+	_svr.AddHandler("A", a)
+	_svr.AddHandler("B", b)
+	return _svr
+}
+
+var IdlJsonRaw = `[
+    {
+        "type": "comment",
+        "value": "Barrister conformance IDL\n\nThe bits in here have silly names and the operations\nare not intended to be useful.  The intent is to\nexercise as much of the IDL grammar as possible"
+    },
+    {
+        "type": "enum",
+        "name": "Status",
+        "comment": "",
+        "values": [
+            {
+                "value": "ok",
+                "comment": ""
+            },
+            {
+                "value": "err",
+                "comment": ""
+            }
+        ]
+    },
+    {
+        "type": "enum",
+        "name": "MathOp",
+        "comment": "",
+        "values": [
+            {
+                "value": "add",
+                "comment": ""
+            },
+            {
+                "value": "multiply",
+                "comment": "mult comment"
+            }
+        ]
+    },
+    {
+        "type": "struct",
+        "name": "Response",
+        "comment": "",
+        "extends": "",
+        "fields": [
+            {
+                "name": "status",
+                "type": "Status",
+                "optional": false,
+                "is_array": false,
+                "comment": ""
+            }
+        ]
+    },
+    {
+        "type": "struct",
+        "name": "RepeatResponse",
+        "comment": "testing struct inheritance",
+        "extends": "Response",
+        "fields": [
+            {
+                "name": "count",
+                "type": "int",
+                "optional": false,
+                "is_array": false,
+                "comment": ""
+            },
+            {
+                "name": "items",
+                "type": "string",
+                "optional": false,
+                "is_array": true,
+                "comment": ""
+            }
+        ]
+    },
+    {
+        "type": "struct",
+        "name": "HiResponse",
+        "comment": "",
+        "extends": "",
+        "fields": [
+            {
+                "name": "hi",
+                "type": "string",
+                "optional": false,
+                "is_array": false,
+                "comment": ""
+            }
+        ]
+    },
+    {
+        "type": "struct",
+        "name": "RepeatRequest",
+        "comment": "",
+        "extends": "",
+        "fields": [
+            {
+                "name": "to_repeat",
+                "type": "string",
+                "optional": false,
+                "is_array": false,
+                "comment": ""
+            },
+            {
+                "name": "count",
+                "type": "int",
+                "optional": false,
+                "is_array": false,
+                "comment": ""
+            },
+            {
+                "name": "force_uppercase",
+                "type": "bool",
+                "optional": false,
+                "is_array": false,
+                "comment": ""
+            }
+        ]
+    },
+    {
+        "type": "struct",
+        "name": "Person",
+        "comment": "",
+        "extends": "",
+        "fields": [
+            {
+                "name": "personId",
+                "type": "string",
+                "optional": false,
+                "is_array": false,
+                "comment": ""
+            },
+            {
+                "name": "firstName",
+                "type": "string",
+                "optional": false,
+                "is_array": false,
+                "comment": ""
+            },
+            {
+                "name": "lastName",
+                "type": "string",
+                "optional": false,
+                "is_array": false,
+                "comment": ""
+            },
+            {
+                "name": "email",
+                "type": "string",
+                "optional": true,
+                "is_array": false,
+                "comment": ""
+            }
+        ]
+    },
+    {
+        "type": "interface",
+        "name": "A",
+        "comment": "",
+        "functions": [
+            {
+                "name": "add",
+                "comment": "returns a+b",
+                "params": [
+                    {
+                        "name": "a",
+                        "type": "int",
+                        "is_array": false
+                    },
+                    {
+                        "name": "b",
+                        "type": "int",
+                        "is_array": false
+                    }
+                ],
+                "returns": {
+                    "type": "int",
+                    "optional": false,
+                    "is_array": false
+                }
+            },
+            {
+                "name": "calc",
+                "comment": "performs the given operation against \nall the values in nums and returns the result",
+                "params": [
+                    {
+                        "name": "nums",
+                        "type": "float",
+                        "is_array": true
+                    },
+                    {
+                        "name": "operation",
+                        "type": "MathOp",
+                        "is_array": false
+                    }
+                ],
+                "returns": {
+                    "type": "float",
+                    "optional": false,
+                    "is_array": false
+                }
+            },
+            {
+                "name": "sqrt",
+                "comment": "returns the square root of a",
+                "params": [
+                    {
+                        "name": "a",
+                        "type": "float",
+                        "is_array": false
+                    }
+                ],
+                "returns": {
+                    "type": "float",
+                    "optional": false,
+                    "is_array": false
+                }
+            },
+            {
+                "name": "repeat",
+                "comment": "Echos the req1.to_repeat string as a list,\noptionally forcing to_repeat to upper case\n\nRepeatResponse.items should be a list of strings\nwhose length is equal to req1.count",
+                "params": [
+                    {
+                        "name": "req1",
+                        "type": "RepeatRequest",
+                        "is_array": false
+                    }
+                ],
+                "returns": {
+                    "type": "RepeatResponse",
+                    "optional": false,
+                    "is_array": false
+                }
+            },
+            {
+                "name": "say_hi",
+                "comment": "returns a result with:\n  hi=\"hi\" and status=\"ok\"",
+                "params": [],
+                "returns": {
+                    "type": "HiResponse",
+                    "optional": false,
+                    "is_array": false
+                }
+            },
+            {
+                "name": "repeat_num",
+                "comment": "returns num as an array repeated 'count' number of times",
+                "params": [
+                    {
+                        "name": "num",
+                        "type": "int",
+                        "is_array": false
+                    },
+                    {
+                        "name": "count",
+                        "type": "int",
+                        "is_array": false
+                    }
+                ],
+                "returns": {
+                    "type": "int",
+                    "optional": false,
+                    "is_array": true
+                }
+            },
+            {
+                "name": "putPerson",
+                "comment": "simply returns p.personId\n\nwe use this to test the '[optional]' enforcement, \nas we invoke it with a null email",
+                "params": [
+                    {
+                        "name": "p",
+                        "type": "Person",
+                        "is_array": false
+                    }
+                ],
+                "returns": {
+                    "type": "string",
+                    "optional": false,
+                    "is_array": false
+                }
+            }
+        ]
+    },
+    {
+        "type": "interface",
+        "name": "B",
+        "comment": "a second interface to prove that the server dispatcher\nunderstands how to distinguish between interfaces in a contract",
+        "functions": [
+            {
+                "name": "echo",
+                "comment": "simply returns s \nif s == \"return-null\" then you should return a null ",
+                "params": [
+                    {
+                        "name": "s",
+                        "type": "string",
+                        "is_array": false
+                    }
+                ],
+                "returns": {
+                    "type": "string",
+                    "optional": true,
+                    "is_array": false
+                }
+            }
+        ]
+    },
+    {
+        "type": "meta",
+        "barrister_version": "0.1.2",
+        "date_generated": 1337654725230,
+        "checksum": "34f6238ed03c6319017382e0fdc638a7"
+    }
+]`
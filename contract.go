@@ -0,0 +1,166 @@
+package barrister
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ChecksumHeader is the HTTP header HttpTransport uses to send the IDL
+// checksum a client was generated from
+const ChecksumHeader = "X-Barrister-Checksum"
+
+// ErrCodeChecksumMismatch is the JSON-RPC error code returned by a Server
+// using ChecksumReject when a client sends a different IDL checksum
+const ErrCodeChecksumMismatch = -32010
+
+// ChecksumPolicy controls what a Server does when a client sends an IDL
+// checksum that differs from the checksum of the server IDL.  Requests
+// without a checksum, and requests to a server whose IDL has no checksum,
+// are never checked.
+type ChecksumPolicy string
+
+const (
+	// ChecksumIgnore does not compare checksums.  This is the default.
+	ChecksumIgnore ChecksumPolicy = "ignore"
+	// ChecksumFlag invokes the method, but marks the request context so
+	// filters and handlers can detect the mismatch with ChecksumMismatch
+	ChecksumFlag ChecksumPolicy = "flag"
+	// ChecksumReject fails the request with ErrCodeChecksumMismatch
+	ChecksumReject ChecksumPolicy = "reject"
+)
+
+// ClientOption configures a RemoteClient
+type ClientOption func(c *RemoteClient)
+
+// WithChecksum sends the given IDL checksum with each request, typically
+// the BarristerChecksum constant of the idl2go generated package.
+//
+// Transports that implement ChecksumTransport (e.g. HttpTransport) send the
+// checksum out of band, otherwise it is sent in the JSON-RPC request.
+func WithChecksum(checksum string) ClientOption {
+	return func(c *RemoteClient) {
+		c.Checksum = checksum
+	}
+}
+
+// ChecksumTransport is implemented by transports that can send the client
+// IDL checksum alongside the request body, such as an HTTP header
+type ChecksumTransport interface {
+	SendChecksumContext(ctx context.Context, in []byte, checksum string) ([]byte, error)
+}
+
+type checksumMismatchKey struct{}
+
+// ChecksumMismatch returns the checksum sent by the client if the request
+// was flagged by a Server using ChecksumFlag.  The bool result is false if
+// the client checksum matched or was not checked.
+func ChecksumMismatch(ctx context.Context) (string, bool) {
+	checksum, ok := ctx.Value(checksumMismatchKey{}).(string)
+	return checksum, ok
+}
+
+// SetChecksumPolicy sets how the Server handles requests from clients
+// generated from a different version of the IDL.  See ChecksumPolicy.
+func (s *Server) SetChecksumPolicy(policy ChecksumPolicy) {
+	s.checksumPolicy = policy
+}
+
+// checkChecksum applies the checksum policy to a request.  It returns the
+// context to invoke the method with, or an error if the request is rejected.
+func (s *Server) checkChecksum(ctx context.Context, headers Headers, rpcReq *JsonRpcRequest) (context.Context, error) {
+	if s.checksumPolicy == "" || s.checksumPolicy == ChecksumIgnore || s.idl.Meta.Checksum == "" {
+		return ctx, nil
+	}
+
+	clientChecksum := rpcReq.Checksum
+	if clientChecksum == "" {
+		clientChecksum = GetFirst(headers.Request, ChecksumHeader)
+	}
+	if clientChecksum == "" || clientChecksum == s.idl.Meta.Checksum {
+		return ctx, nil
+	}
+
+	if s.checksumPolicy == ChecksumReject {
+		msg := fmt.Sprintf("barrister: client IDL checksum %s does not match server IDL checksum %s",
+			clientChecksum, s.idl.Meta.Checksum)
+		data := map[string]string{"client": clientChecksum, "server": s.idl.Meta.Checksum}
+		return ctx, &JsonRpcError{Code: ErrCodeChecksumMismatch, Message: msg, Data: data}
+	}
+	return context.WithValue(ctx, checksumMismatchKey{}, clientChecksum), nil
+}
+
+// ContractError is returned by CheckRemoteIdl when the server IDL has
+// changed in ways that break the client
+type ContractError struct {
+	// Checksum of the IDL the client was generated from
+	LocalChecksum string
+
+	// Checksum of the IDL served by the server
+	RemoteChecksum string
+
+	// Changes that break clients built against the local IDL
+	Changes []CompatChange
+}
+
+func (e *ContractError) Error() string {
+	changes := make([]string, len(e.Changes))
+	for i, c := range e.Changes {
+		changes[i] = c.String()
+	}
+	return fmt.Sprintf("barrister: server IDL is incompatible with client IDL: %s",
+		strings.Join(changes, "; "))
+}
+
+// FetchRemoteIdl calls the "barrister-idl" method and parses the result
+func FetchRemoteIdl(ctx context.Context, c ClientContext) (*Idl, error) {
+	res, err := c.CallContext(ctx, "barrister-idl")
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		return nil, fmt.Errorf("barrister: unable to read barrister-idl response: %s", err)
+	}
+	return ParseIdlJson(b)
+}
+
+// CheckRemoteIdl fetches the IDL from the server and compares it with the
+// local IDL the client was generated from.  A *ContractError is returned if
+// the server contract differs in ways that break the client.  Compatible
+// changes, such as added functions, are allowed.
+func CheckRemoteIdl(ctx context.Context, c ClientContext, local *Idl) error {
+	remote, err := FetchRemoteIdl(ctx, c)
+	if err != nil {
+		return err
+	}
+	if local.Meta.Checksum != "" && local.Meta.Checksum == remote.Meta.Checksum {
+		return nil
+	}
+
+	breaking := []CompatChange{}
+	for _, change := range CompareIdl(local, remote) {
+		if change.BreaksClients {
+			breaking = append(breaking, change)
+		}
+	}
+	if len(breaking) > 0 {
+		return &ContractError{local.Meta.Checksum, remote.Meta.Checksum, breaking}
+	}
+	return nil
+}
+
+// NewCheckedRemoteClient creates a RemoteClient that sends the checksum of
+// local with each request, and fails fast by calling CheckRemoteIdl before
+// returning the client.
+func NewCheckedRemoteClient(ctx context.Context, trans TransportContext, forceASCII bool, local *Idl, opts ...ClientOption) (ClientContext, error) {
+	opts = append([]ClientOption{WithChecksum(local.Meta.Checksum)}, opts...)
+	c := NewRemoteClientContext(trans, forceASCII, opts...)
+	err := CheckRemoteIdl(ctx, c, local)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
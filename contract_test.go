@@ -0,0 +1,142 @@
+package barrister
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+// serverTransport sends requests directly to a Server
+type serverTransport struct {
+	svr *Server
+}
+
+func (t serverTransport) Send(in []byte) ([]byte, error) {
+	return t.svr.InvokeBytes(newHeaders(), in), nil
+}
+
+func newChecksumServer(policy ChecksumPolicy) *Server {
+	svr := NewJSONServer(parseTestIdl(), true)
+	svr.AddHandler("A", AImpl{})
+	svr.AddHandler("B", BImpl{})
+	svr.SetChecksumPolicy(policy)
+	return &svr
+}
+
+func TestChecksumInEnvelope(t *testing.T) {
+	svr := newChecksumServer(ChecksumReject)
+	checksum := svr.idl.Meta.Checksum
+
+	for _, c := range []struct {
+		checksum string
+		errcode  int
+	}{
+		{"", 0},
+		{checksum, 0},
+		{"stale", ErrCodeChecksumMismatch},
+	} {
+		client := NewRemoteClient(serverTransport{svr}, false, WithChecksum(c.checksum))
+		_, err := client.Call("A.add", 1, 2)
+		if c.errcode == 0 {
+			Equals(t, err, nil)
+		} else {
+			Equals(t, err.(*JsonRpcError).Code, c.errcode)
+		}
+
+		resps := client.CallBatch([]JsonRpcRequest{{Jsonrpc: "2.0", Id: "1", Method: "A.add", Params: []interface{}{1, 2}}})
+		Equals(t, len(resps), 1)
+		if c.errcode == 0 {
+			Equals(t, resps[0].Error, (*JsonRpcError)(nil))
+		} else {
+			Equals(t, resps[0].Error.Code, c.errcode)
+		}
+	}
+
+	// barrister-idl is always allowed so clients can negotiate
+	client := NewRemoteClient(serverTransport{svr}, false, WithChecksum("stale"))
+	_, err := client.Call("barrister-idl")
+	Equals(t, err, nil)
+}
+
+func TestChecksumFlag(t *testing.T) {
+	svr := newChecksumServer(ChecksumFlag)
+
+	flagged := []string{}
+	svr.AddFilter(ProxyFilter{
+		func(r *RequestResponse) bool {
+			checksum, ok := ChecksumMismatch(r.Context)
+			if ok {
+				flagged = append(flagged, checksum)
+			}
+			return true
+		},
+		func(r *RequestResponse) bool { return true },
+	})
+
+	for _, checksum := range []string{svr.idl.Meta.Checksum, "stale", ""} {
+		client := NewRemoteClient(serverTransport{svr}, false, WithChecksum(checksum))
+		_, err := client.Call("A.add", 1, 2)
+		Equals(t, err, nil)
+	}
+	DeepEquals(t, flagged, []string{"stale"})
+}
+
+func TestChecksumHttpHeader(t *testing.T) {
+	svr := newChecksumServer(ChecksumReject)
+	httpSvr := httptest.NewServer(svr)
+	defer httpSvr.Close()
+
+	received := ""
+	svr.AddFilter(ProxyFilter{
+		func(r *RequestResponse) bool {
+			received = GetFirst(r.Headers.Request, ChecksumHeader)
+			return true
+		},
+		func(r *RequestResponse) bool { return true },
+	})
+
+	trans := &HttpTransport{Url: httpSvr.URL}
+	client := NewRemoteClient(trans, false, WithChecksum(svr.idl.Meta.Checksum))
+	_, err := client.Call("A.add", 1, 2)
+	Equals(t, err, nil)
+	Equals(t, received, svr.idl.Meta.Checksum)
+
+	client = NewRemoteClient(trans, false, WithChecksum("stale"))
+	_, err = client.Call("A.add", 1, 2)
+	Equals(t, err.(*JsonRpcError).Code, ErrCodeChecksumMismatch)
+}
+
+func TestCheckRemoteIdl(t *testing.T) {
+	svr := newChecksumServer(ChecksumIgnore)
+	trans := transportIgnoreContext{serverTransport{svr}}
+	ctx := context.Background()
+
+	client, err := NewCheckedRemoteClient(ctx, trans, false, parseTestIdl())
+	Equals(t, err, nil)
+	Equals(t, client.(*RemoteClient).Checksum, svr.idl.Meta.Checksum)
+
+	// a client that only uses part of the server contract is compatible
+	subset := MustParseIdl("subset.idl", []byte(`
+interface B {
+	echo(s string) string [optional]
+}
+`))
+	_, err = NewCheckedRemoteClient(ctx, trans, false, subset)
+	Equals(t, err, nil)
+
+	// the server has no D interface
+	missing := MustParseIdl("missing.idl", []byte(`
+interface D {
+	ping() bool
+}
+`))
+	_, err = NewCheckedRemoteClient(ctx, trans, false, missing)
+	contractErr, ok := err.(*ContractError)
+	if !ok {
+		t.Fatalf("Expected *ContractError, got: %v", err)
+	}
+	Equals(t, len(contractErr.Changes), 1)
+	Equals(t, contractErr.Changes[0].Kind, InterfaceRemoved)
+}
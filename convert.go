@@ -1,9 +1,13 @@
 package barrister
 
 import (
+	"bytes"
+	"encoding"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 )
 
 type typeError struct {
@@ -25,6 +29,10 @@ func Convert(idl *Idl, field *Field, desired reflect.Type, actual interface{}, p
 		return nil, err
 	}
 
+	if !conv.IsValid() {
+		// optional pointer or slice that was null
+		return nil, nil
+	}
 	return conv.Interface(), nil
 }
 
@@ -70,6 +78,20 @@ func (c *convert) run() (reflect.Value, error) {
 
 	c.converted = reflect.New(c.desired)
 
+	if fn, ok := converterFor(c.desired); ok {
+		return c.convertWith(fn)
+	}
+
+	if c.desired.Kind() != reflect.String && reflect.PtrTo(c.desired).Implements(textUnmarshalerType) {
+		if s, ok := c.actual.(string); ok {
+			err := c.converted.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+			if err != nil {
+				return zeroVal, &typeError{c.path, err.Error()}
+			}
+			return c.convertedVal()
+		}
+	}
+
 	actVal := reflect.ValueOf(c.actual)
 
 	//fmt.Printf("convert: idl: %s go: %s actual: %s\n", c.field.Type, desiredKind, actType)
@@ -112,35 +134,29 @@ func (c *convert) run() (reflect.Value, error) {
 		if ok {
 			return c.returnVal("string")
 		}
-	case reflect.Int:
-		s, ok := c.actual.(int)
-		if ok {
-			c.converted.Elem().SetInt(int64(s))
-			return c.returnVal("int")
-		}
-		s2, ok := c.actual.(int64)
-		if ok {
-			c.converted.Elem().SetInt(s2)
-			return c.returnVal("int")
-		}
-	case reflect.Int64:
-		s, ok := c.actual.(int64)
-		if ok {
-			c.converted.Elem().SetInt(s)
-			return c.returnVal("int")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		ok := true
+		switch a := c.actual.(type) {
+		case int:
+			i = int64(a)
+		case int32:
+			i = int64(a)
+		case int64:
+			i = a
+		case float64:
+			i = int64(a)
+			ok = float64(i) == a
+		default:
+			ok = false
 		}
-		s2, ok := c.actual.(int)
 		if ok {
-			c.converted.Elem().SetInt(int64(s2))
-			return c.returnVal("int")
-		}
-		s3, ok := c.actual.(float64)
-		if ok {
-			s4 := int64(s3)
-			if float64(s4) == s3 {
-				c.converted.Elem().SetInt(s4)
-				return c.returnVal("int")
+			if c.converted.Elem().OverflowInt(i) {
+				msg := fmt.Sprintf("Value %d overflows %v", i, c.desired)
+				return zeroVal, &typeError{c.path, msg}
 			}
+			c.converted.Elem().SetInt(i)
+			return c.returnVal("int")
 		}
 	case reflect.Float32:
 		s, ok := c.actual.(float32)
@@ -148,6 +164,11 @@ func (c *convert) run() (reflect.Value, error) {
 			c.converted.Elem().SetFloat(float64(s))
 			return c.returnVal("float")
 		}
+		s2, ok := c.actual.(float64)
+		if ok {
+			c.converted.Elem().SetFloat(s2)
+			return c.returnVal("float")
+		}
 	case reflect.Float64:
 		s, ok := c.actual.(float64)
 		if ok {
@@ -197,6 +218,59 @@ func (c *convert) run() (reflect.Value, error) {
 	return zeroVal, &typeError{c.path, msg}
 }
 
+// Converter converts actual, a value decoded from JSON or passed to
+// Server.Call, to the Go type it was registered for with RegisterConverter
+type Converter func(actual interface{}) (interface{}, error)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+var converters = struct {
+	sync.RWMutex
+	m map[reflect.Type]Converter
+}{m: map[reflect.Type]Converter{}}
+
+// RegisterConverter registers fn to convert values to Go type t, for IDL types
+// mapped to Go types that Convert can't build itself.  Types that implement
+// encoding.TextUnmarshaler are converted from strings without a Converter.
+//
+// Converters are used by handlers registered with AddHandler or
+// AddDispatcher, and by CallInto, so generated proxies, alike.
+//
+//	barrister.RegisterConverter(reflect.TypeOf(Money{}), func(actual interface{}) (interface{}, error) {
+//		f, ok := actual.(float64)
+//		if !ok {
+//			return nil, fmt.Errorf("expected a number")
+//		}
+//		return Money{Cents: int64(f * 100)}, nil
+//	})
+func RegisterConverter(t reflect.Type, fn Converter) {
+	converters.Lock()
+	defer converters.Unlock()
+	converters.m[t] = fn
+}
+
+func converterFor(t reflect.Type) (Converter, bool) {
+	converters.RLock()
+	defer converters.RUnlock()
+	fn, ok := converters.m[t]
+	return fn, ok
+}
+
+// convertWith sets c.converted to the result of fn
+func (c *convert) convertWith(fn Converter) (reflect.Value, error) {
+	conv, err := fn(c.actual)
+	if err != nil {
+		return zeroVal, &typeError{c.path, err.Error()}
+	}
+	convVal := reflect.ValueOf(conv)
+	if !convVal.IsValid() || !convVal.Type().AssignableTo(c.desired) {
+		msg := fmt.Sprintf("Converter for %v returned %T", c.desired, conv)
+		return zeroVal, &typeError{c.path, msg}
+	}
+	c.converted.Elem().Set(convVal)
+	return c.convertedVal()
+}
+
 func (c *convert) convertSlice(actVal reflect.Value) (reflect.Value, error) {
 	length := actVal.Len()
 	slice := reflect.MakeSlice(c.desired, length, length)
@@ -240,16 +314,11 @@ func (c *convert) convertStruct(m map[string]interface{}) (reflect.Value, error)
 
 	for _, sField := range idlStruct.allFields {
 		fname := sField.Name
-		goName := fname
-		structField, ok := c.desired.FieldByName(fname)
+		structField, ok := structFieldFor(c.desired, fname)
 		if !ok {
-			goName = capitalize(fname)
-			structField, ok = c.desired.FieldByName(goName)
-			if !ok {
-				msg := fmt.Sprintf("Struct: %v is missing required field: %s",
-					c.desired, goName)
-				return zeroVal, &typeError{path: c.path, msg: msg}
-			}
+			msg := fmt.Sprintf("Struct: %v is missing required field: %s",
+				c.desired, fieldGoName(fname))
+			return zeroVal, &typeError{path: c.path, msg: msg}
 		}
 
 		mval, ok := m[fname]
@@ -269,12 +338,7 @@ func (c *convert) convertStruct(m map[string]interface{}) (reflect.Value, error)
 				return zeroVal, err
 			}
 
-			f := val.Elem().FieldByName(goName)
-			if f == zeroVal {
-				msg := fmt.Sprintf("Instance: %s is missing required field: %s",
-					c.field.Type, goName)
-				return zeroVal, &typeError{path: c.path, msg: msg}
-			}
+			f := val.Elem().FieldByIndex(structField.Index)
 
 			if f.Kind() == reflect.Ptr {
 				if conv.Kind() == reflect.Ptr {
@@ -296,6 +360,49 @@ func (c *convert) convertStruct(m map[string]interface{}) (reflect.Value, error)
 	return c.convertedVal()
 }
 
+// structFieldsByType caches the result of jsonFields for each struct type
+var structFieldsByType sync.Map
+
+// structFieldFor returns the field of struct type t that holds the IDL field
+// name.  The field is found by its json tag, so Go names may differ from the
+// IDL.  Fields without a json tag are matched by name, either as written in
+// the IDL or capitalized.
+func structFieldFor(t reflect.Type, name string) (reflect.StructField, bool) {
+	fields, ok := structFieldsByType.Load(t)
+	if !ok {
+		fields, _ = structFieldsByType.LoadOrStore(t, jsonFields(t))
+	}
+	f, ok := fields.(map[string]reflect.StructField)[name]
+	if !ok {
+		f, ok = t.FieldByName(name)
+		if !ok {
+			f, ok = t.FieldByName(capitalize(name))
+		}
+	}
+	return f, ok
+}
+
+// jsonFields returns the exported fields of struct type t, including those
+// promoted from embedded structs, keyed by the name in their json tag
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for _, f := range reflect.VisibleFields(t) {
+		if f.PkgPath != "" || f.Anonymous {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		// shallower fields hide deeper ones, as in encoding/json
+		prev, ok := fields[name]
+		if !ok || len(f.Index) < len(prev.Index) {
+			fields[name] = f
+		}
+	}
+	return fields
+}
+
 func (c *convert) returnVal(convertedType string) (reflect.Value, error) {
 	if c.field.Type != convertedType {
 		msg := fmt.Sprintf("Type mismatch for '%s' - Expected: %s Got: %v",
@@ -323,6 +430,58 @@ func capitalize(s string) string {
 	return strings.ToUpper(s[0:1]) + s[1:]
 }
 
+// commonInitialisms are written in upper case in Go names generated for fields,
+// following the Go convention, e.g. "user_id" becomes "UserID"
+var commonInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true,
+	"EOF": true, "GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "LHS": true, "QPS": true, "RAM": true, "RHS": true,
+	"RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true,
+	"URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true, "XMPP": true,
+	"XSRF": true, "XSS": true,
+}
+
+// fieldGoName returns the Go name for an IDL struct field or param.  Words
+// separated by underscores or a change from lower to upper case are joined
+// in CamelCase, e.g. "to_repeat" becomes "ToRepeat" and "personId" becomes
+// "PersonID".
+func fieldGoName(s string) string {
+	words := []string{}
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		switch {
+		case i == len(s) || s[i] == '_':
+			if i > start {
+				words = append(words, s[start:i])
+			}
+			start = i + 1
+		case i > start && isUpper(s[i]) && !isUpper(s[i-1]):
+			words = append(words, s[start:i])
+			start = i
+		}
+	}
+
+	name := ""
+	for _, w := range words {
+		upper := strings.ToUpper(w)
+		if commonInitialisms[upper] {
+			name += upper
+		} else {
+			name += capitalize(w)
+		}
+	}
+
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return capitalize(s)
+	}
+	return name
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
 func capitalizeAndStripMatchingPkg(s string, pkgToStrip string) string {
 	ns, name := splitNs(s)
 	if ns == "" {
@@ -333,3 +492,106 @@ func capitalizeAndStripMatchingPkg(s string, pkgToStrip string) string {
 
 	return ns + "." + capitalize(name)
 }
+
+// checkGoValue returns a *typeError if v is not valid for the IDL field once
+// marshaled to JSON, so any Go representation of the value (struct, map,
+// pointer) is handled the same way
+func checkGoValue(idl *Idl, field *Field, v interface{}, path string) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return &typeError{path, err.Error()}
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var generic interface{}
+	err = dec.Decode(&generic)
+	if err != nil {
+		return &typeError{path, err.Error()}
+	}
+	return checkValue(idl, field, generic, path)
+}
+
+// checkValue returns a *typeError if v, a value decoded from JSON with
+// json.Decoder.UseNumber, is not valid for the IDL field
+func checkValue(idl *Idl, field *Field, v interface{}, path string) error {
+	if v == nil {
+		if field.Optional {
+			return nil
+		}
+		return &typeError{path, fmt.Sprintf("%v null not allowed", field)}
+	}
+
+	if field.IsArray {
+		arr, ok := v.([]interface{})
+		if !ok {
+			return &typeError{path, fmt.Sprintf("Expected array of %s, got: %T", field.Type, v)}
+		}
+		elemField := &Field{Name: field.Name, Type: field.Type}
+		for i, el := range arr {
+			err := checkValue(idl, elemField, el, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ok := false
+	switch field.Type {
+	case "string":
+		_, ok = v.(string)
+	case "bool":
+		_, ok = v.(bool)
+	case "float":
+		_, ok = v.(json.Number)
+	case "int":
+		var n json.Number
+		n, ok = v.(json.Number)
+		if ok {
+			_, err := n.Int64()
+			ok = err == nil
+		}
+	default:
+		if enum, isEnum := idl.enums[field.Type]; isEnum {
+			s, isStr := v.(string)
+			if !isStr {
+				break
+			}
+			for _, enumVal := range enum {
+				if enumVal.Value == s {
+					return nil
+				}
+			}
+			return &typeError{path: path, msg: notInEnum(enum, s)}
+		}
+
+		idlStruct, isStruct := idl.structs[field.Type]
+		if !isStruct {
+			msg := fmt.Sprintf("Type not found in IDL: %s", field.Type)
+			return &typeError{path: path, msg: msg}
+		}
+		m, isMap := v.(map[string]interface{})
+		if !isMap {
+			break
+		}
+		for _, sField := range idlStruct.allFields {
+			sField := sField
+			mval, found := m[sField.Name]
+			if !found && !sField.Optional {
+				msg := fmt.Sprintf("Input value: %v is missing required field: %s", m, sField.Name)
+				return &typeError{path: path, msg: msg}
+			}
+			err := checkValue(idl, &sField, mval, path+"."+sField.Name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !ok {
+		msg := fmt.Sprintf("Type mismatch for '%s' - Expected: %s Got: %T", path, field.Type, v)
+		return &typeError{path: path, msg: msg}
+	}
+	return nil
+}
@@ -0,0 +1,646 @@
+package barrister
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// decodeValue unmarshals data, the JSON value of an IDL field, into target,
+// which must be a pointer to the Go type of the field.  The value is checked
+// against the IDL while it is decoded, so a value that is not valid for the
+// field (e.g. a missing required struct field, a null that is not optional,
+// or a string that is not an enum value) is reported as a *typeError without
+// decoding it twice.
+//
+// Go types with a Converter registered are checked and then converted from
+// the value decoded by encoding/json.  Types that implement json.Unmarshaler
+// or encoding.TextUnmarshaler, and types decodeValue can't fill itself such
+// as interface{}, are checked and then unmarshaled with encoding/json.
+func decodeValue(idl *Idl, field *Field, data []byte, target interface{}, path string) error {
+	d := &decoder{idl: idl, data: data, base: path}
+	err := d.value(field, reflect.ValueOf(target).Elem())
+	if err != nil {
+		return err
+	}
+	d.skipSpace()
+	if d.pos < len(d.data) {
+		return d.syntaxError()
+	}
+	return nil
+}
+
+// decoder walks JSON data guided by the IDL.  Values are set on the Go value
+// passed to each method, or only checked if it is not valid (zeroVal).
+type decoder struct {
+	idl  *Idl
+	data []byte
+	pos  int
+
+	// path to the current value, appended to base in errors
+	base string
+	path []pathElem
+}
+
+// pathElem is a struct field name, or an array index if name is empty
+type pathElem struct {
+	name  string
+	index int
+}
+
+func (d *decoder) pathString() string {
+	b := &strings.Builder{}
+	b.WriteString(d.base)
+	for _, p := range d.path {
+		if p.name != "" {
+			b.WriteString("." + p.name)
+		} else {
+			fmt.Fprintf(b, "[%d]", p.index)
+		}
+	}
+	return b.String()
+}
+
+func (d *decoder) errorf(format string, args ...interface{}) error {
+	return &typeError{d.pathString(), fmt.Sprintf(format, args...)}
+}
+
+func (d *decoder) syntaxError() error {
+	if d.pos >= len(d.data) {
+		return d.errorf("unexpected end of JSON input")
+	}
+	return d.errorf("invalid character %q at offset %d", d.data[d.pos], d.pos)
+}
+
+// mismatch returns the error for a JSON value that is not of the IDL type
+func (d *decoder) mismatch(field *Field) error {
+	path := d.pathString()
+	return &typeError{path, fmt.Sprintf("Type mismatch for '%s' - Expected: %s Got: %s",
+		path, field.Type, d.jsonKind())}
+}
+
+// jsonKind names the kind of the JSON value at d.pos
+func (d *decoder) jsonKind() string {
+	if d.pos >= len(d.data) {
+		return "nothing"
+	}
+	switch d.data[d.pos] {
+	case '"':
+		return "string"
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case 't', 'f':
+		return "bool"
+	case 'n':
+		return "null"
+	}
+	return "number"
+}
+
+func (d *decoder) skipSpace() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume skips whitespace and returns true if the next byte is c
+func (d *decoder) consume(c byte) bool {
+	d.skipSpace()
+	if d.pos < len(d.data) && d.data[d.pos] == c {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+func (d *decoder) literal(s string) bool {
+	if len(d.data)-d.pos >= len(s) && string(d.data[d.pos:d.pos+len(s)]) == s {
+		d.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// value decodes the JSON value at d.pos, which must be valid for field
+func (d *decoder) value(field *Field, v reflect.Value) error {
+	d.skipSpace()
+	if d.literal("null") {
+		if !field.Optional {
+			return d.errorf("%v null not allowed", field)
+		}
+		if v.IsValid() {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	if v.IsValid() {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if fn, ok := converterFor(v.Type()); ok {
+			return d.convert(field, v, fn)
+		}
+		if decodeWithJson(v.Type()) {
+			start := d.pos
+			err := d.value(field, zeroVal)
+			if err != nil {
+				return err
+			}
+			err = json.Unmarshal(d.data[start:d.pos], v.Addr().Interface())
+			if err != nil {
+				return d.errorf("%s", err)
+			}
+			return nil
+		}
+	}
+
+	if field.IsArray {
+		return d.array(field, v)
+	}
+
+	switch field.Type {
+	case "string":
+		s, err := d.str(field)
+		if err != nil {
+			return err
+		}
+		return d.setString(v, s)
+	case "int", "float":
+		return d.number(field, v)
+	case "bool":
+		var b bool
+		if d.literal("true") {
+			b = true
+		} else if !d.literal("false") {
+			return d.mismatch(field)
+		}
+		if v.IsValid() {
+			if v.Kind() != reflect.Bool {
+				return d.errorf("Unable to convert bool to %v", v.Type())
+			}
+			v.SetBool(b)
+		}
+		return nil
+	}
+
+	if enum, ok := d.idl.enums[field.Type]; ok {
+		s, err := d.str(field)
+		if err != nil {
+			return err
+		}
+		for _, enumVal := range enum {
+			if enumVal.Value == s {
+				return d.setString(v, s)
+			}
+		}
+		return &typeError{d.pathString(), notInEnum(enum, s)}
+	}
+
+	if s, ok := d.idl.structs[field.Type]; ok {
+		return d.object(s, field, v)
+	}
+	return d.errorf("Type not found in IDL: %s", field.Type)
+}
+
+// convert checks the value at d.pos, and sets v to the result of fn called
+// with the value decoded by encoding/json, as Convert does
+func (d *decoder) convert(field *Field, v reflect.Value, fn Converter) error {
+	start := d.pos
+	err := d.value(field, zeroVal)
+	if err != nil {
+		return err
+	}
+	var actual interface{}
+	err = json.Unmarshal(d.data[start:d.pos], &actual)
+	if err != nil {
+		return d.errorf("%s", err)
+	}
+	conv, err := fn(actual)
+	if err != nil {
+		return d.errorf("%s", err)
+	}
+	convVal := reflect.ValueOf(conv)
+	if !convVal.IsValid() || !convVal.Type().AssignableTo(v.Type()) {
+		return d.errorf("Converter for %v returned %T", v.Type(), conv)
+	}
+	v.Set(convVal)
+	return nil
+}
+
+func (d *decoder) setString(v reflect.Value, s string) error {
+	if v.IsValid() {
+		if v.Kind() != reflect.String {
+			return d.errorf("Unable to convert string to %v", v.Type())
+		}
+		v.SetString(s)
+	}
+	return nil
+}
+
+// str decodes a JSON string
+func (d *decoder) str(field *Field) (string, error) {
+	if d.pos >= len(d.data) || d.data[d.pos] != '"' {
+		return "", d.mismatch(field)
+	}
+	start := d.pos
+	d.pos++
+	plain := true
+	for ; d.pos < len(d.data); d.pos++ {
+		c := d.data[d.pos]
+		switch {
+		case c == '"':
+			d.pos++
+			if plain {
+				return string(d.data[start+1 : d.pos-1]), nil
+			}
+			var s string
+			err := json.Unmarshal(d.data[start:d.pos], &s)
+			if err != nil {
+				return "", d.errorf("%s", err)
+			}
+			return s, nil
+		case c == '\\':
+			plain = false
+			d.pos++
+		case c < 0x20 || c >= 0x80:
+			// let encoding/json reject control characters and
+			// replace invalid UTF-8
+			plain = false
+		}
+	}
+	return "", d.syntaxError()
+}
+
+// number decodes a JSON number into an int or float field
+func (d *decoder) number(field *Field, v reflect.Value) error {
+	start := d.pos
+	for d.pos < len(d.data) && strings.IndexByte("+-.0123456789eE", d.data[d.pos]) >= 0 {
+		d.pos++
+	}
+	b := d.data[start:d.pos]
+	if len(b) == 0 {
+		return d.mismatch(field)
+	}
+	if !isJsonNumber(b) {
+		d.pos = start
+		return d.syntaxError()
+	}
+
+	if field.Type == "int" {
+		i, ok := parseInt(b)
+		if !ok {
+			return d.errorf("Type mismatch for '%s' - Expected: int Got: %s", d.pathString(), b)
+		}
+		if !v.IsValid() {
+			return nil
+		}
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.OverflowInt(i) {
+				return d.errorf("Value %d overflows %v", i, v.Type())
+			}
+			v.SetInt(i)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if i < 0 || v.OverflowUint(uint64(i)) {
+				return d.errorf("Value %d overflows %v", i, v.Type())
+			}
+			v.SetUint(uint64(i))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(float64(i))
+			return nil
+		}
+		return d.errorf("Unable to convert int to %v", v.Type())
+	}
+
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(string(b), v.Type().Bits())
+		if err != nil {
+			return d.errorf("Value %s overflows %v", b, v.Type())
+		}
+		v.SetFloat(f)
+		return nil
+	}
+	return d.errorf("Unable to convert float to %v", v.Type())
+}
+
+// parseInt parses b, a JSON number, as an int64.  It returns false if b has
+// a fraction or exponent, or overflows int64.
+func parseInt(b []byte) (int64, bool) {
+	neg := b[0] == '-'
+	if neg {
+		b = b[1:]
+	}
+	var n uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		if n > (1<<63)/10 {
+			return 0, false
+		}
+		n = n*10 + uint64(c-'0')
+	}
+	if neg {
+		if n > 1<<63 {
+			return 0, false
+		}
+		return -int64(n), true
+	}
+	if n > 1<<63-1 {
+		return 0, false
+	}
+	return int64(n), true
+}
+
+// isJsonNumber returns true if s is a number in the JSON grammar
+func isJsonNumber(s []byte) bool {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	digits := func() int {
+		n := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+			n++
+		}
+		return n
+	}
+	if i < len(s) && s[i] == '0' {
+		i++
+	} else if digits() == 0 {
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+// array decodes a JSON array into a slice
+func (d *decoder) array(field *Field, v reflect.Value) error {
+	if !d.consume('[') {
+		return &typeError{d.pathString(), fmt.Sprintf("Expected array of %s, got: %s", field.Type, d.jsonKind())}
+	}
+	if v.IsValid() {
+		if v.Kind() != reflect.Slice {
+			return d.errorf("Unable to convert array to %v", v.Type())
+		}
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+
+	elemField := &Field{Name: field.Name, Type: field.Type}
+	if d.consume(']') {
+		return nil
+	}
+	for i := 0; ; i++ {
+		elem := zeroVal
+		if v.IsValid() {
+			if i == v.Cap() {
+				grown := reflect.MakeSlice(v.Type(), i, 2*i+4)
+				reflect.Copy(grown, v)
+				v.Set(grown)
+			}
+			v.SetLen(i + 1)
+			elem = v.Index(i)
+		}
+
+		d.path = append(d.path, pathElem{index: i})
+		err := d.value(elemField, elem)
+		if err != nil {
+			return err
+		}
+		d.path = d.path[:len(d.path)-1]
+
+		if d.consume(']') {
+			return nil
+		}
+		if !d.consume(',') {
+			return d.syntaxError()
+		}
+	}
+}
+
+// object decodes a JSON object into a struct.  Keys that are not fields of
+// the IDL struct are skipped.
+func (d *decoder) object(s *Struct, field *Field, v reflect.Value) error {
+	start := d.pos
+	if !d.consume('{') {
+		return d.mismatch(field)
+	}
+	if v.IsValid() && v.Kind() != reflect.Struct {
+		return d.errorf("Unable to convert object to %v", v.Type())
+	}
+
+	var seenBuf [32]bool
+	var seen []bool
+	if len(s.allFields) > len(seenBuf) {
+		seen = make([]bool, len(s.allFields))
+	} else {
+		seen = seenBuf[:len(s.allFields)]
+	}
+
+	if !d.consume('}') {
+		for {
+			d.skipSpace()
+			key, err := d.str(keyField)
+			if err != nil {
+				return d.syntaxError()
+			}
+			if !d.consume(':') {
+				return d.syntaxError()
+			}
+
+			x := -1
+			for i := range s.allFields {
+				if s.allFields[i].Name == key {
+					x = i
+					break
+				}
+			}
+			if x < 0 {
+				err = d.skip()
+			} else {
+				seen[x] = true
+				fieldVal := zeroVal
+				if v.IsValid() {
+					fieldVal = structFieldValue(v, key)
+				}
+				d.path = append(d.path, pathElem{name: key})
+				err = d.value(&s.allFields[x], fieldVal)
+				d.path = d.path[:len(d.path)-1]
+			}
+			if err != nil {
+				return err
+			}
+
+			if d.consume('}') {
+				break
+			}
+			if !d.consume(',') {
+				return d.syntaxError()
+			}
+		}
+	}
+
+	for i, sField := range s.allFields {
+		if !seen[i] && !sField.Optional {
+			var m map[string]interface{}
+			json.Unmarshal(d.data[start:d.pos], &m)
+			return d.errorf("Input value: %v is missing required field: %s", m, sField.Name)
+		}
+	}
+	return nil
+}
+
+// structFieldValue returns the field of struct v that holds the IDL field
+// name, allocating embedded struct pointers on the way, or zeroVal if the
+// Go struct has no such field
+func structFieldValue(v reflect.Value, name string) reflect.Value {
+	sf, ok := structFieldFor(v.Type(), name)
+	if !ok {
+		return zeroVal
+	}
+	for i, x := range sf.Index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return zeroVal
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// skip skips a JSON value of any type
+func (d *decoder) skip() error {
+	d.skipSpace()
+	if d.pos >= len(d.data) {
+		return d.syntaxError()
+	}
+	switch d.data[d.pos] {
+	case '"':
+		_, err := d.str(keyField)
+		return err
+	case '{':
+		d.pos++
+		if d.consume('}') {
+			return nil
+		}
+		for {
+			d.skipSpace()
+			_, err := d.str(keyField)
+			if err != nil || !d.consume(':') {
+				return d.syntaxError()
+			}
+			if err = d.skip(); err != nil {
+				return err
+			}
+			if d.consume('}') {
+				return nil
+			}
+			if !d.consume(',') {
+				return d.syntaxError()
+			}
+		}
+	case '[':
+		d.pos++
+		if d.consume(']') {
+			return nil
+		}
+		for {
+			if err := d.skip(); err != nil {
+				return err
+			}
+			if d.consume(']') {
+				return nil
+			}
+			if !d.consume(',') {
+				return d.syntaxError()
+			}
+		}
+	}
+	if d.literal("true") || d.literal("false") || d.literal("null") {
+		return nil
+	}
+	return d.number(numberField, zeroVal)
+}
+
+// keyField and numberField are used to decode object keys and skipped values
+var keyField = &Field{Type: "string"}
+var numberField = &Field{Type: "float"}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// decodeWithJsonTypes caches the result of decodeWithJson for each type
+var decodeWithJsonTypes sync.Map
+
+// decodeWithJson returns true if values of type t are unmarshaled with
+// encoding/json, because they decode themselves or aren't a kind decoder
+// fills itself
+func decodeWithJson(t reflect.Type) bool {
+	withJson, ok := decodeWithJsonTypes.Load(t)
+	if !ok {
+		ptr := reflect.PtrTo(t)
+		withJson = ptr.Implements(jsonUnmarshalerType) || ptr.Implements(textUnmarshalerType)
+		switch t.Kind() {
+		case reflect.Bool, reflect.String, reflect.Slice, reflect.Struct,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			withJson = true
+		}
+		decodeWithJsonTypes.Store(t, withJson)
+	}
+	return withJson.(bool)
+}
+
+// notInEnum returns the error message for s, which is not one of the values
+// of enum
+func notInEnum(enum []EnumValue, s string) string {
+	msg := fmt.Sprintf("Value '%s' not in enum values: ", s)
+	for x, enumVal := range enum {
+		if x > 0 {
+			msg += ", "
+		}
+		msg += "'" + enumVal.Value + "'"
+	}
+	return msg
+}
@@ -0,0 +1,97 @@
+package barrister
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+type embeddedRepeatResponse struct {
+	*Response
+	Count int      `json:"count"`
+	Items []string `json:"items"`
+}
+
+func TestDecodeValue(t *testing.T) {
+	idl := parseTestIdl()
+	person := &Field{Type: "Person"}
+
+	var p Person
+	err := decodeValue(idl, person, []byte(` {"personId": "pé1", "firstName": "a", "lastName": "b\n",
+		"email": "x@y", "unknown": [{"a": [1, true, null]}]} `), &p, "")
+	Equals(t, err, nil)
+	Equals(t, p.PersonId, "pé1")
+	Equals(t, p.LastName, "b\n")
+	Equals(t, *p.Email, "x@y")
+
+	err = decodeValue(idl, person, []byte(`{"personId":"p1","firstName":"a","lastName":"b","email":null}`), &p, "")
+	Equals(t, err, nil)
+	Equals(t, p.Email, (*string)(nil))
+
+	var r embeddedRepeatResponse
+	err = decodeValue(idl, &Field{Type: "RepeatResponse"}, []byte(`{"status":"ok","count":2,"items":["a","b"]}`), &r, "")
+	Equals(t, err, nil)
+	Equals(t, r.Status, StatusOk)
+	Equals(t, r.Count, 2)
+	DeepEquals(t, r.Items, []string{"a", "b"})
+
+	var nums []float64
+	err = decodeValue(idl, &Field{Type: "float", IsArray: true}, []byte(`[1, -2.5, 3e2]`), &nums, "")
+	Equals(t, err, nil)
+	DeepEquals(t, nums, []float64{1, -2.5, 300})
+
+	var empty []int64
+	err = decodeValue(idl, &Field{Type: "int", IsArray: true}, []byte(`[]`), &empty, "")
+	Equals(t, err, nil)
+	DeepEquals(t, empty, []int64{})
+
+	var big int64
+	err = decodeValue(idl, &Field{Type: "int"}, []byte(`-9007199254740993`), &big, "")
+	Equals(t, err, nil)
+	Equals(t, big, int64(-9007199254740993))
+
+	var generic interface{}
+	err = decodeValue(idl, &Field{Type: "HiResponse"}, []byte(`{"hi":"there"}`), &generic, "")
+	Equals(t, err, nil)
+	DeepEquals(t, generic, map[string]interface{}{"hi": "there"})
+}
+
+func TestDecodeValueErrors(t *testing.T) {
+	idl := parseTestIdl()
+	for _, c := range []struct {
+		field  Field
+		data   string
+		target interface{}
+		err    string
+	}{
+		{Field{Type: "Person"}, `{"personId":"p1"}`, &Person{}, "param[0]: Input value: map[personId:p1] is missing required field: firstName"},
+		{Field{Type: "Person"}, `{"personId":"p1","firstName":null}`, &Person{}, "param[0].firstName: "},
+		{Field{Type: "Person"}, `{"personId":5}`, &Person{}, "Type mismatch for 'param[0].personId' - Expected: string Got: number"},
+		{Field{Type: "Person"}, `{"personId":"p1",}`, &Person{}, "invalid character"},
+		{Field{Type: "Person"}, `{"personId":"p1"`, &Person{}, "unexpected end of JSON input"},
+		{Field{Type: "Person"}, `[]`, &Person{}, "Expected: Person Got: array"},
+		{Field{Type: "int"}, `null`, new(int64), "null not allowed"},
+		{Field{Type: "int"}, `1.5`, new(int64), "Expected: int Got: 1.5"},
+		{Field{Type: "int"}, `01`, new(int64), "invalid character"},
+		{Field{Type: "int"}, `300`, new(int8), "Value 300 overflows int8"},
+		{Field{Type: "int"}, `9223372036854775808`, new(int64), "Expected: int"},
+		{Field{Type: "int"}, `1 2`, new(int64), "invalid character '2'"},
+		{Field{Type: "string"}, `"a`, new(string), "unexpected end of JSON input"},
+		{Field{Type: "string"}, `5`, new(int), "Expected: string Got: number"},
+		{Field{Type: "bool"}, `"true"`, new(bool), "Expected: bool Got: string"},
+		{Field{Type: "string", IsArray: true}, `["a",null]`, &[]string{}, "param[0][1]: "},
+		{Field{Type: "string", IsArray: true}, `"a"`, &[]string{}, "Expected array of string, got: string"},
+		{Field{Type: "MathOp"}, `"divide"`, new(MathOp), "Value 'divide' not in enum values: 'add', 'multiply'"},
+		{Field{Type: "MathOp"}, `"add"`, new(int), "Unable to convert string to int"},
+		{Field{Type: "HiResponse"}, `{"hi":5}`, new(interface{}), "Expected: string Got: number"},
+	} {
+		err := decodeValue(idl, &c.field, []byte(c.data), c.target, "param[0]")
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expected error containing %q, got: %v", c.data, c.err, err)
+		}
+		if _, ok := err.(*typeError); !ok {
+			t.Errorf("%s: expected *typeError, got: %T", c.data, err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package barrister
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Dispatcher calls the functions of an IDL interface on a handler without
+// using reflection.  idl2go generates a Dispatcher for each interface, e.g.
+// "UserServiceDispatcher", which is registered with Server.AddDispatcher.
+type Dispatcher interface {
+	// Decode converts params to the Go types of the given method, which is
+	// fully qualified (e.g. "UserService.save"), and returns a function that
+	// invokes the method with them.
+	//
+	// When the Server decoded the request itself and has no filters, each
+	// param is a json.RawMessage that DecodeParam decodes directly into its
+	// Go type.
+	Decode(idl *Idl, method string, params []interface{}) (DispatchFunc, error)
+}
+
+// DispatchFunc invokes a method with params decoded by a Dispatcher on the
+// given handler
+type DispatchFunc func(ctx context.Context, handler interface{}) (interface{}, error)
+
+// AddDispatcher associates the given impl with the IDL interface, like
+// AddHandler, but invokes impl through the given Dispatcher instead of
+// using reflection.
+//
+// impl is checked against the IDL like it is by AddHandler, and AddDispatcher
+// panics if it does not implement the interface.  Filters, Cloneable and
+// context parameters behave as they do for handlers registered with
+// AddHandler.  Params are decoded straight into their Go types and checked
+// against the IDL as they are decoded.
+//
+// If the Server has filters, the params are also decoded as generic values
+// for Filter.PreInvoke, as they are for other handlers, and the Dispatcher
+// converts the params the filters leave in RequestResponse.Params.
+//
+// Typically called with an idl2go generated Dispatcher, e.g.:
+//
+//	svr.AddDispatcher("UserService", impl, usersvc.UserServiceDispatcher{})
+func (s *Server) AddDispatcher(iface string, impl interface{}, d Dispatcher) {
+	s.validateImpl(iface, impl)
+	s.handlers[iface] = impl
+	s.dispatchers[iface] = d
+}
+
+// DecodeParam is called by idl2go generated Dispatchers to convert params[i]
+// of method to target, which must be a pointer to the Go type of the param.
+//
+// json.RawMessage params are decoded directly into target, and checked
+// against the IDL as they are decoded.  Other params, such as the values
+// passed to Server.Call, are converted with Convert.
+func DecodeParam(idl *Idl, method string, params []interface{}, i int, target interface{}) error {
+	path := fmt.Sprintf("param[%d]", i)
+	field := idl.Method(method).Params[i]
+
+	raw, ok := params[i].(json.RawMessage)
+	if !ok {
+		targetVal := reflect.ValueOf(target).Elem()
+		conv, err := Convert(idl, &field, targetVal.Type(), params[i], path)
+		if err != nil {
+			return &JsonRpcError{Code: -32602, Message: err.Error()}
+		}
+		if conv != nil {
+			targetVal.Set(reflect.ValueOf(conv))
+		}
+		return nil
+	}
+
+	err := decodeValue(idl, &field, raw, target, path)
+	if err != nil {
+		return &JsonRpcError{Code: -32602, Message: err.Error()}
+	}
+	return nil
+}
+
+// genericParams returns params with each json.RawMessage param unmarshaled
+// by the Serializer as a generic value, as params are passed to filters for
+// other handlers
+func (s *Server) genericParams(params []interface{}) ([]interface{}, error) {
+	decoded := make([]interface{}, len(params))
+	for i, p := range params {
+		raw, ok := p.(json.RawMessage)
+		if !ok {
+			decoded[i] = p
+			continue
+		}
+		err := s.ser.Unmarshal(raw, &decoded[i])
+		if err != nil {
+			err = &typeError{fmt.Sprintf("param[%d]", i), err.Error()}
+			return nil, &JsonRpcError{Code: -32602, Message: err.Error()}
+		}
+	}
+	return decoded, nil
+}
+
+// requestParams returns the params of rpcReq.  Params the Serializer left as
+// raw JSON are split into a json.RawMessage per param if the method will be
+// handled by a Dispatcher, and unmarshaled as generic values otherwise.
+func (s *Server) requestParams(rpcReq *JsonRpcRequest) []interface{} {
+	switch p := rpcReq.Params.(type) {
+	case []interface{}:
+		return p
+	case *json.RawMessage:
+		if len(*p) == 0 {
+			return nil
+		}
+		iface, _ := parseMethod(rpcReq.Method)
+		if _, ok := s.dispatchers[iface]; ok {
+			raw := []json.RawMessage{}
+			if s.ser.Unmarshal(*p, &raw) != nil {
+				return nil
+			}
+			params := make([]interface{}, len(raw))
+			for i, r := range raw {
+				params[i] = r
+			}
+			return params
+		}
+
+		params := []interface{}{}
+		if s.ser.Unmarshal(*p, &params) != nil {
+			return nil
+		}
+		return params
+	}
+	return nil
+}
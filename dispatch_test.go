@@ -0,0 +1,244 @@
+package barrister
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+// aDispatcher is written the way idl2go generates dispatchers, for the
+// conform.idl functions exercised below
+type aDispatcher struct{}
+
+func (aDispatcher) Decode(_idl *Idl, _method string, _params []interface{}) (DispatchFunc, error) {
+	switch _method {
+	case "A.add":
+		var a int64
+		if _err := DecodeParam(_idl, _method, _params, 0, &a); _err != nil {
+			return nil, _err
+		}
+		var b int64
+		if _err := DecodeParam(_idl, _method, _params, 1, &b); _err != nil {
+			return nil, _err
+		}
+		return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+			return _handler.(AImpl).Add(a, b)
+		}, nil
+	case "A.putPerson":
+		var p Person
+		if _err := DecodeParam(_idl, _method, _params, 0, &p); _err != nil {
+			return nil, _err
+		}
+		return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+			return _handler.(AImpl).PutPerson(p)
+		}, nil
+	}
+	return nil, &JsonRpcError{Code: -32601, Message: fmt.Sprintf("Unsupported method: %s", _method)}
+}
+
+type bDispatcher struct{}
+
+func (bDispatcher) Decode(_idl *Idl, _method string, _params []interface{}) (DispatchFunc, error) {
+	switch _method {
+	case "B.echo":
+		var s string
+		if _err := DecodeParam(_idl, _method, _params, 0, &s); _err != nil {
+			return nil, _err
+		}
+		return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+			return _handler.(BImpl).Echo(s)
+		}, nil
+	}
+	return nil, &JsonRpcError{Code: -32601, Message: fmt.Sprintf("Unsupported method: %s", _method)}
+}
+
+func invokeJson(t *testing.T, svr *Server, req string) string {
+	return string(svr.InvokeBytes(newHeaders(), []byte(req)))
+}
+
+func TestDispatcherMatchesHandler(t *testing.T) {
+	reflected := NewJSONServer(parseTestIdl(), true)
+	reflected.AddHandler("A", AImpl{})
+	reflected.AddHandler("B", BImpl{})
+
+	dispatched := NewJSONServer(parseTestIdl(), true)
+	dispatched.AddDispatcher("A", AImpl{}, aDispatcher{})
+	dispatched.AddDispatcher("B", BImpl{}, bDispatcher{})
+
+	for _, req := range []string{
+		`{"jsonrpc":"2.0","id":"1","method":"A.add","params":[3,4]}`,
+		`{"jsonrpc":"2.0","id":"1","method":"A.add","params":[3]}`,
+		`{"jsonrpc":"2.0","id":"1","method":"A.add","params":[3,null]}`,
+		`{"jsonrpc":"2.0","id":"1","method":"A.putPerson","params":[{"personId":"p1","firstName":"a","lastName":"b","email":null}]}`,
+		`{"jsonrpc":"2.0","id":"1","method":"A.putPerson","params":[{"personId":"p1"}]}`,
+		`{"jsonrpc":"2.0","id":"1","method":"A.putPerson","params":[{"personId":"p1","firstName":null,"lastName":"b"}]}`,
+		`{"jsonrpc":"2.0","id":"1","method":"B.echo","params":["hi"]}`,
+		`{"jsonrpc":"2.0","id":"1","method":"B.echo","params":["return-null"]}`,
+		`{"jsonrpc":"2.0","id":"1","method":"B.echo"}`,
+		`[{"jsonrpc":"2.0","id":"1","method":"A.add","params":[1,2]},{"jsonrpc":"2.0","id":"2","method":"B.echo","params":["x"]}]`,
+	} {
+		Equals(t, invokeJson(t, &dispatched, req), invokeJson(t, &reflected, req))
+	}
+
+	// params that are not valid for the Go type are rejected
+	resp := JsonRpcResponse{}
+	json.Unmarshal([]byte(invokeJson(t, &dispatched, `{"jsonrpc":"2.0","id":"1","method":"A.add","params":["x",1]}`)), &resp)
+	Equals(t, resp.Error.Code, -32602)
+}
+
+func TestDispatcherWithServerCall(t *testing.T) {
+	svr := NewJSONServer(parseTestIdl(), true)
+	svr.AddDispatcher("A", AImpl{}, aDispatcher{})
+
+	// generic params, as passed in process, are converted
+	res, err := svr.Call(newHeaders(), "A.add", int64(5), float64(6))
+	Equals(t, err, nil)
+	Equals(t, res, int64(11))
+}
+
+func TestDispatcherFiltersAndClone(t *testing.T) {
+	svr := NewJSONServer(parseTestIdl(), true)
+	svr.AddDispatcher("B", BImpl{}, bDispatcher{})
+
+	var handler interface{}
+	svr.AddFilter(ProxyFilter{
+		func(r *RequestResponse) bool {
+			handler = r.Handler
+			r.Handler.(BImpl).context.UserId = 42
+			return r.Params[0] == "get-userid"
+		},
+		func(r *RequestResponse) bool {
+			r.Result = fmt.Sprintf("%s!", *r.Result.(*string))
+			return true
+		},
+	})
+
+	Equals(t, invokeJson(t, &svr, `{"jsonrpc":"2.0","id":"1","method":"B.echo","params":["get-userid"]}`),
+		`{"jsonrpc":"2.0","id":"1","result":"42!"}`)
+	Equals(t, handler.(BImpl).cloned, true)
+}
+
+func TestDispatcherFiltersMatchHandler(t *testing.T) {
+	preInvoked := 0
+	filter := ProxyFilter{
+		func(r *RequestResponse) bool {
+			preInvoked++
+			if r.Params[0] == "rewrite" {
+				r.Params[0] = "rewritten"
+			}
+			return true
+		},
+		func(r *RequestResponse) bool {
+			return true
+		},
+	}
+
+	reflected := NewJSONServer(parseTestIdl(), true)
+	reflected.AddHandler("A", AImpl{})
+	reflected.AddHandler("B", BImpl{})
+	reflected.AddFilter(filter)
+
+	dispatched := NewJSONServer(parseTestIdl(), true)
+	dispatched.AddDispatcher("A", AImpl{}, aDispatcher{})
+	dispatched.AddDispatcher("B", BImpl{}, bDispatcher{})
+	dispatched.AddFilter(filter)
+
+	for _, req := range []string{
+		`{"jsonrpc":"2.0","id":"1","method":"B.echo","params":["rewrite"]}`,
+		`{"jsonrpc":"2.0","id":"1","method":"A.add","params":[3,4]}`,
+		`{"jsonrpc":"2.0","id":"1","method":"A.putPerson","params":[{"personId":"p1"}]}`,
+	} {
+		preInvoked = 0
+		Equals(t, invokeJson(t, &dispatched, req), invokeJson(t, &reflected, req))
+		Equals(t, preInvoked, 2)
+	}
+
+	Equals(t, invokeJson(t, &dispatched, `{"jsonrpc":"2.0","id":"1","method":"B.echo","params":["rewrite"]}`),
+		`{"jsonrpc":"2.0","id":"1","result":"rewritten"}`)
+}
+
+func TestAddDispatcherChecksImpl(t *testing.T) {
+	svr := NewJSONServer(parseTestIdl(), true)
+
+	fx := func() {
+		defer func() {
+			if r := recover(); r != nil {
+				// ok
+			}
+		}()
+		svr.AddDispatcher("A", BImpl{}, aDispatcher{})
+		t.Errorf("AddDispatcher didn't panic when called w/impl of another interface")
+	}
+	fx()
+}
+
+const benchIdl = `
+struct Person {
+	personId  string
+	firstName string
+	lastName  string
+	email     string [optional]
+}
+
+interface People {
+	count(people []Person) int
+}
+`
+
+type peopleImpl struct{}
+
+func (peopleImpl) Count(people []Person) (int64, error) {
+	return int64(len(people)), nil
+}
+
+type peopleDispatcher struct{}
+
+func (peopleDispatcher) Decode(_idl *Idl, _method string, _params []interface{}) (DispatchFunc, error) {
+	switch _method {
+	case "People.count":
+		var people []Person
+		if _err := DecodeParam(_idl, _method, _params, 0, &people); _err != nil {
+			return nil, _err
+		}
+		return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+			return _handler.(peopleImpl).Count(people)
+		}, nil
+	}
+	return nil, &JsonRpcError{Code: -32601, Message: fmt.Sprintf("Unsupported method: %s", _method)}
+}
+
+// benchmarkPeople invokes People.count with 200 people on svr
+func benchmarkPeople(b *testing.B, svr *Server) {
+	people := make([]Person, 200)
+	email := "a@b.c"
+	for i := range people {
+		people[i] = Person{fmt.Sprintf("p%d", i), "first", "last", &email}
+	}
+	params, _ := json.Marshal([]interface{}{people})
+	req := []byte(`{"jsonrpc":"2.0","id":"1","method":"People.count","params":` + string(params) + `}`)
+	expected := `{"jsonrpc":"2.0","id":"1","result":200}`
+	if string(svr.InvokeBytes(newHeaders(), req)) != expected {
+		b.Fatalf("unexpected response: %s", svr.InvokeBytes(newHeaders(), req))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		svr.InvokeBytes(newHeaders(), req)
+	}
+}
+
+func BenchmarkHandler(b *testing.B) {
+	svr := NewJSONServer(MustParseIdl("people.idl", []byte(benchIdl)), false)
+	svr.AddHandler("People", peopleImpl{})
+	benchmarkPeople(b, &svr)
+}
+
+func BenchmarkDispatcher(b *testing.B) {
+	svr := NewJSONServer(MustParseIdl("people.idl", []byte(benchIdl)), false)
+	svr.AddDispatcher("People", peopleImpl{}, peopleDispatcher{})
+	benchmarkPeople(b, &svr)
+}
@@ -0,0 +1,383 @@
+package barrister
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// docModel is the IDL reshaped for rendering API documentation
+type docModel struct {
+	Title      string
+	Comments   []string
+	Interfaces []docInterface
+	Structs    []docStruct
+	Enums      []Enum
+}
+
+type docInterface struct {
+	Name      string
+	Comment   string
+	Functions []docFunction
+}
+
+type docFunction struct {
+	Name    string
+	Method  string
+	Comment string
+	Params  []Field
+	Returns Field
+
+	// example JSON-RPC payloads
+	Request  string
+	Response string
+}
+
+type docStruct struct {
+	Name    string
+	Comment string
+	Extends string
+	Fields  []docField
+}
+
+type docField struct {
+	Field
+
+	// name of the struct the field is declared on, if it is inherited
+	InheritedFrom string
+}
+
+func newDocModel(idl *Idl, title string) *docModel {
+	m := &docModel{Title: title, Comments: idl.Comments(), Enums: idl.Enums()}
+
+	for _, iface := range idl.Interfaces() {
+		di := docInterface{Name: iface.Name, Comment: iface.Comment}
+		for _, fn := range iface.Functions {
+			method := iface.Name + "." + fn.Name
+			req, resp := exampleRpc(idl, method, fn)
+			di.Functions = append(di.Functions,
+				docFunction{fn.Name, method, fn.Comment, fn.Params, fn.Returns, req, resp})
+		}
+		m.Interfaces = append(m.Interfaces, di)
+	}
+
+	for _, s := range idl.Structs() {
+		ds := docStruct{Name: s.Name, Comment: s.Comment, Extends: s.Extends}
+		ds.Fields = docFields(idl, s, map[string]bool{})
+		m.Structs = append(m.Structs, ds)
+	}
+
+	return m
+}
+
+// docFields returns the fields of s, parent fields first, recording which
+// struct each inherited field came from
+func docFields(idl *Idl, s *Struct, seen map[string]bool) []docField {
+	seen[s.Name] = true
+	fields := []docField{}
+	if parent, ok := idl.structs[s.Extends]; ok && !seen[parent.Name] {
+		for _, f := range docFields(idl, parent, seen) {
+			if f.InheritedFrom == "" {
+				f.InheritedFrom = parent.Name
+			}
+			fields = append(fields, f)
+		}
+	}
+	for _, f := range s.Fields {
+		fields = append(fields, docField{Field: f})
+	}
+	return fields
+}
+
+// exampleRpc returns example JSON-RPC request and response payloads for fn
+func exampleRpc(idl *Idl, method string, fn Function) (string, string) {
+	params := make([]interface{}, len(fn.Params))
+	for i, p := range fn.Params {
+		params[i] = p.testVal(idl)
+	}
+	req := JsonRpcRequest{Jsonrpc: "2.0", Id: "1", Method: method, Params: params}
+	resp := JsonRpcResponse{Jsonrpc: "2.0", Id: "1", Result: fn.Returns.testVal(idl)}
+
+	reqBytes, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	respBytes, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(reqBytes), string(respBytes)
+}
+
+// GenerateMarkdown renders API documentation for the IDL as a single
+// Markdown document.  Interfaces list their functions with parameter and
+// return types, which link to the struct and enum sections, along with
+// example JSON-RPC payloads.  Structs include fields inherited via extends.
+func (idl *Idl) GenerateMarkdown(title string) []byte {
+	m := newDocModel(idl, title)
+	b := &bytes.Buffer{}
+
+	line(b, 0, "# "+title)
+	for _, c := range m.Comments {
+		line(b, 0, "")
+		line(b, 0, mdParagraph(c))
+	}
+
+	if len(m.Interfaces) > 0 {
+		line(b, 0, "\n## Interfaces")
+		for _, iface := range m.Interfaces {
+			line(b, 0, fmt.Sprintf("\n### %s", iface.Name))
+			if iface.Comment != "" {
+				line(b, 0, "\n"+mdParagraph(iface.Comment))
+			}
+			line(b, 0, "\n| Function | Params | Returns | Description |")
+			line(b, 0, "|---|---|---|---|")
+			for _, fn := range iface.Functions {
+				params := make([]string, len(fn.Params))
+				for i, p := range fn.Params {
+					params[i] = fmt.Sprintf("`%s` %s", p.Name, mdType(idl, p))
+				}
+				line(b, 0, fmt.Sprintf("| [%s](#%s) | %s | %s | %s |", fn.Name, docAnchor("function", fn.Method),
+					strings.Join(params, ", "), mdType(idl, fn.Returns), mdCell(firstLine(fn.Comment))))
+			}
+			for _, fn := range iface.Functions {
+				line(b, 0, fmt.Sprintf("\n<a name=\"%s\"></a>", docAnchor("function", fn.Method)))
+				line(b, 0, fmt.Sprintf("#### %s", fn.Method))
+				if fn.Comment != "" {
+					line(b, 0, "\n"+mdParagraph(fn.Comment))
+				}
+				line(b, 0, "\nRequest:\n\n```json\n"+fn.Request+"\n```")
+				line(b, 0, "\nResponse:\n\n```json\n"+fn.Response+"\n```")
+			}
+		}
+	}
+
+	if len(m.Structs) > 0 {
+		line(b, 0, "\n## Structs")
+		for _, s := range m.Structs {
+			line(b, 0, fmt.Sprintf("\n<a name=\"%s\"></a>", docAnchor("struct", s.Name)))
+			line(b, 0, fmt.Sprintf("### %s", s.Name))
+			if s.Extends != "" {
+				line(b, 0, fmt.Sprintf("\nExtends %s", mdType(idl, Field{Type: s.Extends})))
+			}
+			if s.Comment != "" {
+				line(b, 0, "\n"+mdParagraph(s.Comment))
+			}
+			line(b, 0, "\n| Field | Type | Description |")
+			line(b, 0, "|---|---|---|")
+			for _, f := range s.Fields {
+				desc := mdCell(f.Comment)
+				if f.InheritedFrom != "" {
+					desc = strings.TrimSpace(fmt.Sprintf("%s (inherited from %s)", desc, mdType(idl, Field{Type: f.InheritedFrom})))
+				}
+				line(b, 0, fmt.Sprintf("| `%s` | %s | %s |", f.Name, mdType(idl, f.Field), desc))
+			}
+		}
+	}
+
+	if len(m.Enums) > 0 {
+		line(b, 0, "\n## Enums")
+		for _, e := range m.Enums {
+			line(b, 0, fmt.Sprintf("\n<a name=\"%s\"></a>", docAnchor("enum", e.Name)))
+			line(b, 0, fmt.Sprintf("### %s", e.Name))
+			if e.Comment != "" {
+				line(b, 0, "\n"+mdParagraph(e.Comment))
+			}
+			line(b, 0, "\n| Value | Description |")
+			line(b, 0, "|---|---|")
+			for _, v := range e.Values {
+				line(b, 0, fmt.Sprintf("| `%s` | %s |", v.Value, mdCell(v.Comment)))
+			}
+		}
+	}
+
+	return b.Bytes()
+}
+
+// GenerateHtml renders API documentation for the IDL as a static HTML site.
+// A map is returned whose keys are file names and values are the page contents:
+// "index.html" links to a page per interface, named "interface-<name>.html",
+// and to "types.html", which documents every struct and enum.
+func (idl *Idl) GenerateHtml(title string) map[string][]byte {
+	m := newDocModel(idl, title)
+	tmpl := template.Must(template.New("doc").Funcs(template.FuncMap{
+		"type": func(f Field) template.HTML {
+			return htmlType(idl, f)
+		},
+		"typeName": func(name string) template.HTML {
+			return htmlType(idl, Field{Type: name})
+		},
+		"ifacePage": htmlInterfacePage,
+		"anchor":    docAnchor,
+		"comment":   htmlComment,
+		"firstLine": firstLine,
+	}).Parse(htmlTemplates))
+
+	pages := map[string][]byte{}
+	render := func(name string, page string, data interface{}) {
+		b := &bytes.Buffer{}
+		err := tmpl.ExecuteTemplate(b, page, map[string]interface{}{"Title": title, "Data": data})
+		if err != nil {
+			panic(err)
+		}
+		pages[name] = b.Bytes()
+	}
+
+	render("index.html", "index", m)
+	render("types.html", "types", m)
+	for _, iface := range m.Interfaces {
+		render(htmlInterfacePage(iface.Name), "interface", iface)
+	}
+	return pages
+}
+
+func docAnchor(kind string, name string) string {
+	return kind + "-" + name
+}
+
+func htmlInterfacePage(name string) string {
+	return "interface-" + name + ".html"
+}
+
+// htmlType renders the type of f, linking structs and enums to types.html
+func htmlType(idl *Idl, f Field) template.HTML {
+	name := template.HTMLEscapeString(f.Type)
+	kind := idl.FieldKind(f)
+	if kind == KindStruct || kind == KindEnum {
+		name = fmt.Sprintf(`<a href="types.html#%s">%s</a>`, docAnchor(string(kind), name), name)
+	}
+	if f.IsArray {
+		name = "[]" + name
+	}
+	if f.Optional {
+		name += " [optional]"
+	}
+	return template.HTML(name)
+}
+
+func htmlComment(comment string) template.HTML {
+	return template.HTML(strings.Replace(template.HTMLEscapeString(comment), "\n", "<br>\n", -1))
+}
+
+// mdType renders the type of f, linking structs and enums to their sections
+func mdType(idl *Idl, f Field) string {
+	name := f.Type
+	kind := idl.FieldKind(f)
+	if kind == KindStruct || kind == KindEnum {
+		name = fmt.Sprintf("[%s](#%s)", name, docAnchor(string(kind), name))
+	}
+	if f.IsArray {
+		// escaped so "[]" is not read as part of a link
+		name = `\[\]` + name
+	}
+	if f.Optional {
+		name += " [optional]"
+	}
+	return name
+}
+
+// mdParagraph renders an IDL comment as Markdown, keeping its line breaks
+func mdParagraph(comment string) string {
+	return strings.Replace(strings.TrimRight(comment, " \n"), "\n", "  \n", -1)
+}
+
+// mdCell renders an IDL comment so it can be used in a Markdown table cell
+func mdCell(comment string) string {
+	s := strings.TrimSpace(comment)
+	s = strings.Replace(s, "|", `\|`, -1)
+	return strings.Replace(s, "\n", "<br>", -1)
+}
+
+// firstLine returns the first line of a comment, used as a summary
+func firstLine(comment string) string {
+	i := strings.Index(comment, "\n")
+	if i > -1 {
+		return strings.TrimSpace(comment[:i])
+	}
+	return strings.TrimSpace(comment)
+}
+
+const htmlTemplates = `
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 60em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+pre { background: #f5f5f5; padding: 0.6em; overflow: auto; }
+.comment { color: #555; }
+</style>
+</head>
+<body>
+<p><a href="index.html">{{.Title}}</a></p>
+{{end}}
+
+{{define "footer"}}</body>
+</html>
+{{end}}
+
+{{define "index"}}{{template "header" .}}
+<h1>{{.Title}}</h1>
+{{range .Data.Comments}}<p class="comment">{{comment .}}</p>
+{{end}}
+{{if .Data.Interfaces}}<h2>Interfaces</h2>
+<ul>
+{{range .Data.Interfaces}}<li><a href="{{ifacePage .Name}}">{{.Name}}</a> {{firstLine .Comment}}</li>
+{{end}}</ul>
+{{end}}
+{{if .Data.Structs}}<h2>Structs</h2>
+<ul>
+{{range .Data.Structs}}<li>{{typeName .Name}} {{firstLine .Comment}}</li>
+{{end}}</ul>
+{{end}}
+{{if .Data.Enums}}<h2>Enums</h2>
+<ul>
+{{range .Data.Enums}}<li>{{typeName .Name}} {{firstLine .Comment}}</li>
+{{end}}</ul>
+{{end}}
+{{template "footer"}}{{end}}
+
+{{define "interface"}}{{template "header" .}}
+{{with .Data}}<h1>{{.Name}}</h1>
+{{if .Comment}}<p class="comment">{{comment .Comment}}</p>{{end}}
+<table>
+<tr><th>Function</th><th>Params</th><th>Returns</th><th>Description</th></tr>
+{{range .Functions}}<tr><td><a href="#{{anchor "function" .Method}}">{{.Name}}</a></td><td>{{range $i, $p := .Params}}{{if $i}}, {{end}}<code>{{$p.Name}}</code> {{type $p}}{{end}}</td><td>{{type .Returns}}</td><td>{{firstLine .Comment}}</td></tr>
+{{end}}</table>
+{{range .Functions}}
+<h2 id="{{anchor "function" .Method}}">{{.Method}}</h2>
+{{if .Comment}}<p class="comment">{{comment .Comment}}</p>{{end}}
+<h3>Request</h3>
+<pre>{{.Request}}</pre>
+<h3>Response</h3>
+<pre>{{.Response}}</pre>
+{{end}}{{end}}
+{{template "footer"}}{{end}}
+
+{{define "types"}}{{template "header" .}}
+{{with .Data}}{{if .Structs}}<h1>Structs</h1>
+{{range .Structs}}
+<h2 id="{{anchor "struct" .Name}}">{{.Name}}</h2>
+{{if .Extends}}<p>Extends {{typeName .Extends}}</p>{{end}}
+{{if .Comment}}<p class="comment">{{comment .Comment}}</p>{{end}}
+<table>
+<tr><th>Field</th><th>Type</th><th>Description</th></tr>
+{{range .Fields}}<tr><td><code>{{.Name}}</code></td><td>{{type .Field}}</td><td>{{comment .Comment}}{{if .InheritedFrom}}{{if .Comment}} {{end}}(inherited from {{typeName .InheritedFrom}}){{end}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}
+{{if .Enums}}<h1>Enums</h1>
+{{range .Enums}}
+<h2 id="{{anchor "enum" .Name}}">{{.Name}}</h2>
+{{if .Comment}}<p class="comment">{{comment .Comment}}</p>{{end}}
+<table>
+<tr><th>Value</th><th>Description</th></tr>
+{{range .Values}}<tr><td><code>{{.Value}}</code></td><td>{{comment .Comment}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}{{end}}
+{{template "footer"}}{{end}}
+`
@@ -0,0 +1,92 @@
+package barrister
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestGenerateMarkdown(t *testing.T) {
+	md := string(MustParseIdl("svc.idl", []byte(`
+// Base type
+struct Base {
+	// unique id
+	id string
+}
+
+struct Item extends Base {
+	tags []Tag [optional]
+}
+
+enum Tag {
+	// the a tag
+	a
+	b
+}
+
+// Stores items
+interface Store {
+	// saves an item
+	// and returns its id
+	save(item Item) string
+}
+`)).GenerateMarkdown("Store API"))
+
+	for _, expected := range []string{
+		"# Store API\n",
+		"### Store\n\nStores items\n",
+		"| [save](#function-Store.save) | `item` [Item](#struct-Item) | string | saves an item |",
+		"#### Store.save\n\nsaves an item  \nand returns its id\n",
+		`"method": "Store.save"`,
+		`"result": "testval"`,
+		"Extends [Base](#struct-Base)",
+		"| `id` | string | unique id (inherited from [Base](#struct-Base)) |",
+		"| `tags` | \\[\\][Tag](#enum-Tag) [optional] |  |",
+		"| `a` | the a tag |",
+	} {
+		if !strings.Contains(md, expected) {
+			t.Errorf("Markdown does not contain %q:\n%s", expected, md)
+		}
+	}
+}
+
+func TestGenerateHtml(t *testing.T) {
+	pages := parseTestIdl().GenerateHtml("<conform>")
+
+	names := []string{}
+	for name := range pages {
+		names = append(names, name)
+	}
+	Equals(t, len(pages), 4)
+	for _, name := range []string{"index.html", "types.html", "interface-A.html", "interface-B.html"} {
+		if _, ok := pages[name]; !ok {
+			t.Errorf("Missing page %s in %v", name, names)
+		}
+	}
+
+	for name, expected := range map[string][]string{
+		"index.html": {
+			"<title>&lt;conform&gt;</title>",
+			`<a href="interface-A.html">A</a>`,
+			`<a href="types.html#struct-Person">Person</a>`,
+		},
+		"interface-A.html": {
+			`<h2 id="function-A.repeat">A.repeat</h2>`,
+			`<code>req1</code> <a href="types.html#struct-RepeatRequest">RepeatRequest</a>`,
+			`&#34;method&#34;: &#34;A.repeat&#34;`,
+		},
+		"types.html": {
+			`<p>Extends <a href="types.html#struct-Response">Response</a></p>`,
+			`(inherited from <a href="types.html#struct-Response">Response</a>)`,
+			`<tr><td><code>multiply</code></td><td>mult comment</td></tr>`,
+		},
+	} {
+		page := string(pages[name])
+		for _, s := range expected {
+			if !strings.Contains(page, s) {
+				t.Errorf("%s does not contain %q:\n%s", name, s, page)
+			}
+		}
+	}
+}
@@ -2,10 +2,12 @@ package barrister
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
+	"go/format"
+	"go/scanner"
 	"sort"
 	"strings"
+	"text/template"
 )
 
 var reservedWords []string = []string{
@@ -47,264 +49,307 @@ type generateGo struct {
 	// imports to add
 	imports []string
 
-	// base import string to prefix to imports values
-	baseImport string
+	// if true, mocks and fake clients are generated for interfaces
+	mocks bool
+
+	// optional mapping of IDL types and fields to existing Go types
+	types *TypeMapping
+
+	// import paths of the mapped Go types used in this package
+	typeImports map[string]bool
+
+	// all the settings the code is generated with, passed to templates
+	opts GenerateOptions
+
+	// the default templates, with those in opts.Templates parsed over them
+	templates *template.Template
 }
 
 func (g *generateGo) hasInterface() bool {
 	return len(g.pkgIdl.interfaces) > 0
 }
 
-func (g *generateGo) generate() []byte {
-	b := &bytes.Buffer{}
-	line(b, 0, fmt.Sprintf("// Code generated by idl2go from JSON generated by Barrister v%s", g.idl.Meta.BarristerVersion))
-	line(b, 0, fmt.Sprintf("package %s\n", g.pkgName))
-	line(b, 0, "import (")
-	if g.hasInterface() {
-		line(b, 1, `"fmt"`)
-		line(b, 1, `"reflect"`)
-		line(b, 1, `"github.com/coopernurse/barrister-go"`)
+// goType returns the Go type of f, which may be mapped to an existing Go type.
+// key is the name of f in TypeMapping.Fields, see paramKey, or empty for
+// function return values.
+func (g *generateGo) goType(f Field, key string) string {
+	t, ok := g.mappedType(f.Type, key)
+	if !ok {
+		return f.goType(g.idl, g.optionalToPtr, g.pkgName)
 	}
-	for _, imp := range g.imports {
-		line(b, 1, fmt.Sprintf("\"%s%s\"", g.baseImport, imp))
+
+	if f.IsArray {
+		if f.Optional && g.optionalToPtr {
+			return "*[]" + t
+		}
+		return "[]" + t
 	}
-	if g.includeContext != IncludeContextNo && g.hasInterface() {
-		line(b, 1, `"context"`)
+	_, isStruct := g.idl.structs[f.Type]
+	if f.Optional && (g.optionalToPtr || isStruct) {
+		return "*" + t
 	}
-	line(b, 0, ")\n")
+	return t
+}
 
-	if g.hasInterface() {
-		line(b, 0, "const BarristerVersion string = \""+g.idl.Meta.BarristerVersion+"\"")
-		line(b, 0, "const BarristerChecksum string = \""+g.idl.Meta.Checksum+"\"")
-		line(b, 0, fmt.Sprintf("const BarristerDateGenerated int64 = %d", g.idl.Meta.DateGenerated))
-		line(b, 0, "")
+// zeroVal returns the zero value of the Go type of f, see goType
+func (g *generateGo) zeroVal(f Field, key string) string {
+	if _, ok := g.mappedType(f.Type, key); !ok {
+		return fmt.Sprintf("%v", f.zeroVal(g.idl, g.optionalToPtr, g.pkgName))
 	}
+	return fmt.Sprintf("*new(%s)", g.goType(f, key))
+}
 
-	for name, _ := range g.pkgIdl.enums {
-		g.generateEnum(b, name)
+// mappedType returns the Go type that the field key, or else the IDL type
+// idlType, is mapped to, and records its import
+func (g *generateGo) mappedType(idlType string, key string) (string, bool) {
+	if g.types == nil {
+		return "", false
 	}
-
-	for _, elem := range g.pkgIdl.elems {
-		if elem.Type == "struct" {
-			s, ok := g.pkgIdl.structs[elem.Name]
-			if !ok {
-				panic("No struct found: " + elem.Name)
-			}
-			g.generateStruct(b, s)
-		}
+	t, ok := g.types.Fields[key]
+	if !ok || key == "" {
+		t, ok = g.types.Types[idlType]
+	}
+	if !ok {
+		return "", false
 	}
-	line(b, 0, "")
 
-	if g.hasInterface() {
-		for _, name := range sortedKeys(g.pkgIdl.interfaces) {
-			g.generateInterfaceAndProxy(b, name)
-		}
+	imp, name := splitGoType(t)
+	if imp != "" {
+		g.typeImports[imp] = true
+	}
+	return name, true
+}
 
-		g.generateNewServer(b, false)
-		if g.includeContext == IncludeContextBoth {
-			g.generateNewServer(b, true)
-		}
-		g.generateIdlJson(b)
+// isMapped returns true if the IDL struct or enum is mapped to a Go type, so
+// no Go type is generated for it
+func (g *generateGo) isMapped(idlType string) bool {
+	if g.types == nil {
+		return false
 	}
+	_, ok := g.types.Types[idlType]
+	return ok
+}
 
-	return b.Bytes()
+// paramKey returns the name of param p of fn in TypeMapping.Fields
+func paramKey(ifaceName string, fn Function, p Field) string {
+	return ifaceName + "." + fn.Name + "." + p.Name
 }
 
-func (g *generateGo) generateIdlJson(b *bytes.Buffer) {
-	idlbytes, err := json.MarshalIndent(g.idl.elems, "", "    ")
-	if err != nil {
-		panic(err)
+// elemComment returns the comment of the IDL element with the given type and name
+func (g *generateGo) elemComment(elemType string, name string) string {
+	for _, elem := range g.idl.elems {
+		if elem.Type == elemType && elem.Name == name {
+			return elem.Comment
+		}
 	}
-	idlstr := strings.Replace(string(idlbytes), "`", "`+\"`\"+`", -1)
-	line(b, 0, "")
-	line(b, 0, "var IdlJsonRaw = `"+idlstr+"`")
+	return ""
 }
 
-func (g *generateGo) generateEnum(b *bytes.Buffer, enumName string) {
-	vals, ok := g.idl.enums[enumName]
-	if !ok {
-		panic("No enum found: " + enumName)
-	}
+// TypeMapping maps IDL types to existing Go types in generated code, instead
+// of the types idl2go would generate or use for them.
+//
+// Go types are written as the import path and name of the type, e.g.
+// "github.com/google/uuid.UUID", or just the name for builtin types such as
+// "int32".  The package name must be the last element of the import path.
+//
+// The server converts request values to mapped types with Convert, which
+// supports all integer and float types, types registered with
+// RegisterConverter, and types that implement encoding.TextUnmarshaler.
+type TypeMapping struct {
+	// Maps IDL types to Go types everywhere they are used.  Keys are builtin
+	// IDL types ("int") or the names of structs or enums, which are then not
+	// generated.
+	Types map[string]string `json:"types"`
+
+	// Maps individual struct fields and function params, which take
+	// precedence over Types.  Keys are "Struct.field" or
+	// "Interface.function.param".
+	Fields map[string]string `json:"fields"`
+}
 
-	goName := capitalizeAndStripMatchingPkg(enumName, g.pkgName)
-	line(b, 0, fmt.Sprintf("type %s string", goName))
-	line(b, 0, "const (")
-	for _, val := range vals {
-		line(b, 1, fmt.Sprintf("%s%s %s = \"%s\"",
-			goName, capitalize(val.Value), goName, val.Value))
+// check returns an error if the mapping refers to types or fields that are
+// not in idl
+func (m *TypeMapping) check(idl *Idl) error {
+	for name := range m.Types {
+		_, isStruct := idl.structs[name]
+		_, isEnum := idl.enums[name]
+		if !isStruct && !isEnum && !isBuiltin(name) {
+			return fmt.Errorf("barrister: type mapping: IDL has no type: %s", name)
+		}
 	}
-	line(b, 0, ")\n")
-}
 
-func (g *generateGo) generateStruct(b *bytes.Buffer, s *Struct) {
-	goName := capitalizeAndStripMatchingPkg(s.Name, g.pkgName)
-	line(b, 0, fmt.Sprintf("type %s struct {", goName))
-	if s.Extends != "" {
-		line(b, 1, capitalizeAndStripMatchingPkg(s.Extends, g.pkgName))
+	keys := map[string]bool{}
+	for _, s := range idl.structs {
+		for _, f := range s.Fields {
+			keys[s.Name+"."+f.Name] = true
+		}
+	}
+	for ifaceName, funcs := range idl.interfaces {
+		for _, fn := range funcs {
+			for _, p := range fn.Params {
+				keys[paramKey(ifaceName, fn, p)] = true
+			}
+		}
 	}
-	for _, f := range s.Fields {
-		goName = capitalize(f.Name)
-		omit := ""
-		if f.Optional {
-			omit = ",omitempty"
+	for key := range m.Fields {
+		if !keys[key] {
+			return fmt.Errorf("barrister: type mapping: IDL has no struct field or param: %s", key)
 		}
-		line(b, 1, fmt.Sprintf("%s\t%s\t`json:\"%s%s\"`",
-			goName, f.goType(g.idl, g.optionalToPtr, g.pkgName), f.Name, omit))
 	}
-	line(b, 0, "}\n")
+	return nil
 }
 
-func (g *generateGo) generateNewServer(b *bytes.Buffer, withContextSuffix bool) {
-	ifaceKeys := sortedKeys(g.idl.interfaces)
-	ifaces := make([]string, 0, len(ifaceKeys))
-	ifaceNames := make([]string, 0, len(ifaceKeys))
-	ifaceIdents := make([]string, 0, len(ifaceKeys))
-	for _, name := range ifaceKeys {
-		upper := capitalize(name)
-		lower := escReserved(strings.ToLower(name))
-		if withContextSuffix {
-			upper += withContextIfaceNameSuffix
-		}
-		ifaces = append(ifaces, fmt.Sprintf("%s %s", lower, upper))
-		ifaceNames = append(ifaceNames, upper)
-		ifaceIdents = append(ifaceIdents, lower)
+// mapped returns the Go type the IDL type idlType is mapped to.  m may be nil.
+func (m *TypeMapping) mapped(idlType string) (string, bool) {
+	if m == nil {
+		return "", false
 	}
-	contextSuffix := ""
-	if withContextSuffix {
-		contextSuffix = withContextIfaceNameSuffix
+	t, ok := m.Types[idlType]
+	return t, ok
+}
+
+// splitGoType splits a Go type in a TypeMapping into its import path and its
+// name as used in generated code, e.g. "github.com/google/uuid.UUID" is
+// split into "github.com/google/uuid" and "uuid.UUID"
+func splitGoType(t string) (string, string) {
+	dot := strings.LastIndex(t, ".")
+	if dot < 0 {
+		return "", t
 	}
-	line(b, 0, fmt.Sprintf("func NewJSONServer%s(idl *barrister.Idl, forceASCII bool, %s) barrister.Server {", contextSuffix, strings.Join(ifaces, ", ")))
-	line(b, 1, fmt.Sprintf("return NewServer%s(idl, &barrister.JsonSerializer{forceASCII}, %s)", contextSuffix, strings.Join(ifaceIdents, ", ")))
-	line(b, 0, "}\n")
-
-	line(b, 0, fmt.Sprintf("func NewServer%s(idl *barrister.Idl, ser barrister.Serializer, %s) barrister.Server {", contextSuffix, strings.Join(ifaces, ", ")))
-	line(b, 1, fmt.Sprintf("_svr := barrister.NewServer(idl, ser)"))
-	for i, name := range ifaceNames {
-		line(b, 1, fmt.Sprintf("_svr.AddHandler(\"%s\", %s)", name, ifaceIdents[i]))
+	imp := t[:dot]
+	return imp, imp[strings.LastIndex(imp, "/")+1:] + t[dot:]
+}
+
+func isBuiltin(idlType string) bool {
+	switch idlType {
+	case "string", "int", "float", "bool":
+		return true
 	}
-	line(b, 1, "return _svr")
-	line(b, 0, "}")
+	return false
 }
 
-const withContextIfaceNameSuffix = "WithContext"
+// GenerateError is returned by GenerateGoSource if the code generated for a package
+// is not valid Go
+type GenerateError struct {
+	// Go package the code was generated for
+	Package string
 
-func (g *generateGo) generateInterfaceAndProxy(b *bytes.Buffer, ifaceName string) {
-	funcs, ok := g.idl.interfaces[ifaceName]
-	if !ok {
-		panic("No interface found: " + ifaceName)
-	}
+	// Syntax error reported by go/format
+	Err error
 
-	includeContext := g.includeContext == IncludeContextYes
-	g.generateInterface(b, ifaceName, funcs, includeContext)
-	line(b, 0, "}\n")
-	g.generateProxy(b, ifaceName, funcs, includeContext)
+	// Line of generated code the syntax error occurred on, if known
+	Line string
+}
 
-	if g.includeContext == IncludeContextBoth {
-		nameWithContext := ifaceName + withContextIfaceNameSuffix
-		g.generateInterface(b, nameWithContext, funcs, true)
-		line(b, 0, "}\n")
-		g.generateProxy(b, nameWithContext, funcs, true)
+func (e *GenerateError) Error() string {
+	msg := fmt.Sprintf("barrister: generated code for package %s is not valid Go: %s", e.Package, e.Err)
+	if e.Line != "" {
+		msg += "\n\t" + strings.TrimSpace(e.Line)
 	}
+	return msg
 }
 
-func (g *generateGo) generateInterface(b *bytes.Buffer, ifaceName string, funcs []Function, includeContext bool) {
-	goName := capitalize(ifaceName)
-	line(b, 0, fmt.Sprintf("type %s interface {", goName))
-	for _, fn := range funcs {
-		goName = capitalize(fn.Name)
+// formatGo runs the generated code through go/format, which also checks it
+// for syntax errors
+func formatGo(pkgName string, code []byte) ([]byte, error) {
+	formatted, err := format.Source(code)
+	if err == nil {
+		return formatted, nil
+	}
 
-		numParams := len(fn.Params)
-		if includeContext {
-			numParams++
-		}
-		params := make([]string, 0, numParams)
-		if includeContext {
-			params = append(params, "ctx context.Context")
-		}
-		for _, p := range fn.Params {
-			params = append(params, fmt.Sprintf("%s %s", escReserved(p.Name), p.goType(g.idl, g.optionalToPtr, g.pkgName)))
+	genErr := &GenerateError{Package: pkgName, Err: err}
+	if errs, ok := err.(scanner.ErrorList); ok && len(errs) > 0 {
+		lines := strings.Split(string(code), "\n")
+		if n := errs[0].Pos.Line; n > 0 && n <= len(lines) {
+			genErr.Line = lines[n-1]
 		}
-
-		line(b, 1, fmt.Sprintf("%s(%s) (%s, error)",
-			goName, strings.Join(params, ", "), fn.Returns.goType(g.idl, g.optionalToPtr, g.pkgName)))
 	}
+	return nil, genErr
 }
 
-func (g *generateGo) generateProxy(b *bytes.Buffer, ifaceName string, funcs []Function, includeContext bool) {
-	goIfaceName := capitalize(ifaceName)
-	goName := goIfaceName + "Proxy"
-
-	contextSuffix := ""
+// funcParams returns the Go params of the interface method for fn, a
+// function of the IDL interface ifaceName
+func (g *generateGo) funcParams(ifaceName string, fn Function, includeContext bool) []string {
+	params := make([]string, 0, len(fn.Params)+1)
 	if includeContext {
-		contextSuffix = "Context"
+		params = append(params, "ctx context.Context")
+	}
+	for _, p := range fn.Params {
+		params = append(params, fmt.Sprintf("%s %s", escReserved(p.Name), g.goType(p, paramKey(ifaceName, fn, p))))
 	}
+	return params
+}
 
-	line(b, 0, fmt.Sprintf("func New%s(c barrister.Client%s) %s { return %s{c, barrister.MustParseIdlJson([]byte(IdlJsonRaw))} }\n", goName, contextSuffix, goIfaceName, goName))
-
-	line(b, 0, fmt.Sprintf("type %s struct {", goName))
-	line(b, 1, "client barrister.Client"+contextSuffix)
-	line(b, 1, "idl    *barrister.Idl")
-	line(b, 0, "}\n")
-	for _, fn := range funcs {
-		method := fmt.Sprintf("%s.%s", ifaceName, fn.Name)
-		retType := fn.Returns.goType(g.idl, g.optionalToPtr, g.pkgName)
-		zeroVal := fn.Returns.zeroVal(g.idl, g.optionalToPtr, g.pkgName)
-		fnName := capitalize(fn.Name)
-
-		numParams := len(fn.Params)
-		if includeContext {
-			numParams++
-		}
-		params := make([]string, 0, numParams)
-		paramIdents := make([]string, 0, numParams)
-		if includeContext {
-			params = append(params, "ctx context.Context")
+// checkGoNames returns an error if two IDL names that are generated in the
+// same Go scope get the same Go name, e.g. the struct fields "user_id" and
+// "userId", which are both generated as UserID
+func checkGoNames(idl *Idl, opts GenerateOptions) error {
+	structNames := make([]string, 0, len(idl.structs))
+	for name := range idl.structs {
+		structNames = append(structNames, name)
+	}
+	sort.Strings(structNames)
+
+	for _, name := range structNames {
+		s := idl.structs[name]
+		if _, ok := opts.Types.mapped(name); ok {
+			continue
 		}
-		for _, p := range fn.Params {
-			ident := escReserved(p.Name)
-			params = append(params, fmt.Sprintf("%s %s", ident, p.goType(g.idl, g.optionalToPtr, g.pkgName)))
-			paramIdents = append(paramIdents, ident)
+		names := goNames{scope: name, names: map[string]string{}}
+		if s.Extends != "" {
+			embedded := capitalize(s.Extends[strings.LastIndex(s.Extends, ".")+1:])
+			if t, ok := opts.Types.mapped(s.Extends); ok {
+				embedded = t[strings.LastIndex(t, ".")+1:]
+			}
+			names.add(embedded, "embedded struct "+s.Extends)
 		}
-
-		line(b, 0, fmt.Sprintf("func (_p %s) %s(%s) (%s, error) {",
-			goName, fnName, strings.Join(params, ", "), retType))
-
-		contextArg := ""
-		if includeContext {
-			contextArg = "ctx, "
+		for _, f := range s.allFields {
+			err := names.add(fieldGoName(f.Name), "field "+f.Name)
+			if err != nil {
+				return err
+			}
 		}
+	}
 
-		line(b, 1, fmt.Sprintf("_res, _err := _p.client.Call%s(%s\"%s\", %s)",
-			contextSuffix, contextArg, method, strings.Join(paramIdents, ", ")))
-		line(b, 1, "if _err == nil {")
-		if g.optionalToPtr && fn.Returns.Optional {
-			line(b, 2, "if _res == nil {")
-			line(b, 3, "return nil, nil")
-			line(b, 2, "}")
+	includeContext := opts.IncludeContext == IncludeContextYes || opts.IncludeContext == IncludeContextBoth
+	for _, ifaceName := range sortedKeys(idl.interfaces) {
+		for _, fn := range idl.interfaces[ifaceName] {
+			scope := ifaceName + "." + fn.Name
+			params := goNames{scope: scope, names: map[string]string{}}
+			// fields of the mock's Call struct
+			fields := goNames{scope: scope, names: map[string]string{}}
+			if includeContext {
+				params.add("ctx", "context param")
+				fields.add("Ctx", "context param")
+			}
+			for _, p := range fn.Params {
+				err := params.add(escReserved(p.Name), "param "+p.Name)
+				if err == nil && opts.Mocks {
+					err = fields.add(fieldGoName(p.Name), "param "+p.Name)
+				}
+				if err != nil {
+					return err
+				}
+			}
 		}
-		line(b, 2, fmt.Sprintf("_retType := _p.idl.Method(\"%s\").Returns", method))
-		line(b, 2, fmt.Sprintf("_res, _err = barrister.Convert(_p.idl, &_retType, reflect.TypeOf(%s), _res, \"\")", zeroVal))
-		line(b, 1, "}")
-		line(b, 1, "if _err == nil {")
-		line(b, 2, fmt.Sprintf("_cast, _ok := _res.(%s)", retType))
-		line(b, 2, "if !_ok {")
-		line(b, 3, "_t := reflect.TypeOf(_res)")
-		line(b, 3, `_msg := fmt.Sprintf("`+method+` returned invalid type: %v", _t)`)
-		line(b, 3, fmt.Sprintf("return %s, &barrister.JsonRpcError{Code: -32000, Message: _msg}", zeroVal))
-		line(b, 2, "}")
-		line(b, 2, "return _cast, nil")
-		line(b, 1, "}")
-		line(b, 1, fmt.Sprintf("return %s, _err", zeroVal))
-		line(b, 0, "}\n")
 	}
+	return nil
 }
 
-func comment(b *bytes.Buffer, level int, comment string) {
-	if comment != "" {
-		for _, ln := range strings.Split(comment, "\n") {
-			line(b, level, fmt.Sprintf("// %s", ln))
-		}
+// goNames maps the Go names in a scope to the IDL names they were generated
+// from
+type goNames struct {
+	scope string
+	names map[string]string
+}
+
+// add returns an error naming both IDL names if goName is already used
+func (n goNames) add(goName string, idlName string) error {
+	if other, ok := n.names[goName]; ok {
+		return fmt.Errorf("barrister: %s: %s and %s have the same Go name: %s", n.scope, other, idlName, goName)
 	}
+	n.names[goName] = idlName
+	return nil
 }
 
 func line(b *bytes.Buffer, level int, s string) {
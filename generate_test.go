@@ -3,7 +3,19 @@ package barrister
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	gotoken "go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	. "github.com/couchbaselabs/go.assert"
 )
 
 func TestGenerateEnum(t *testing.T) {
@@ -24,16 +36,13 @@ func TestGenerateEnum(t *testing.T) {
 		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
 			t.Parallel()
 
-			g := &generateGo{
-				idl: &Idl{
-					enums: map[string][]EnumValue{
-						"asdf": tc.enums,
-					},
-				},
-			}
+			g := &generateGo{idl: &Idl{}}
 
 			res := &bytes.Buffer{}
-			g.generateEnum(res, "asdf")
+			err := g.execute(res, "enum", Enum{Name: "asdf", Values: tc.enums})
+			if err != nil {
+				t.Fatal(err)
+			}
 
 			if string(res.Bytes()) != string(tc.res) {
 				t.Errorf("Expected %s, got %s", tc.res, res.Bytes())
@@ -41,3 +50,511 @@ func TestGenerateEnum(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateGoComments(t *testing.T) {
+	idl := MustParseIdl("svc.idl", []byte(`
+// Package docs
+//
+// second paragraph
+
+// the status
+enum Status {
+	// all good
+	ok
+}
+
+// a user
+struct User {
+	// unique id   
+	id string
+}
+
+// manages users
+interface Users {
+	// returns the user
+	get(id string) User
+}
+`))
+	code := string(idl.GenerateGo("svc", "", false, IncludeContextBoth)["svc"])
+
+	for _, expected := range []string{
+		"\n// Package docs\n//\n// second paragraph\npackage svc\n",
+		"// the status\ntype Status string\n",
+		"\t// all good\n\tStatusOk Status",
+		"// a user\ntype User struct {\n\t// unique id\n\tID string `json:\"id\"`\n}",
+		"// manages users\ntype Users interface {\n\t// returns the user\n\tGet(",
+		"// manages users\ntype UsersWithContext interface {\n\t// returns the user\n\tGet(",
+		"// returns the user\nfunc (_p UsersProxy) Get(",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Generated code does not contain %q:\n%s", expected, code)
+		}
+	}
+}
+
+func TestGenerateGoHeader(t *testing.T) {
+	for _, src := range []string{"// Package docs\n\ninterface I { f() int }", "interface I { f() int }"} {
+		idl := MustParseIdl("svc.idl", []byte(src))
+		code := idl.GenerateGo("svc", "", false, IncludeContextNo)["svc"]
+
+		lines := strings.SplitN(string(code), "\n", 3)
+		Equals(t, lines[0], "// Code generated by idl2go. DO NOT EDIT.")
+		Equals(t, lines[1], "// Source: Barrister IDL v"+ParserVersion)
+
+		// the header is not part of the package doc
+		f, err := parser.ParseFile(gotoken.NewFileSet(), "svc.go", code, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Doc != nil && strings.Contains(f.Doc.Text(), "Code generated") {
+			t.Errorf("Package doc contains the header: %q", f.Doc.Text())
+		}
+	}
+}
+
+func TestGenerateGoDispatcher(t *testing.T) {
+	idl := MustParseIdl("svc.idl", []byte(`
+interface Users {
+	get(id string) string
+}
+`))
+	code := string(idl.GenerateGo("svc", "", false, IncludeContextBoth)["svc"])
+
+	for _, expected := range []string{
+		"type UsersDispatcher struct{}\n",
+		"type UsersWithContextDispatcher struct{}\n",
+		"\tcase \"Users.get\":\n\t\tvar id string\n\t\tif _err := barrister.DecodeParam(_idl, _method, _params, 0, &id); _err != nil {",
+		"\t\t\treturn _h.Get(id)\n",
+		"\t\t\treturn _h.Get(_ctx, id)\n",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Generated code does not contain %q:\n%s", expected, code)
+		}
+	}
+}
+
+func TestGenerateGoProxy(t *testing.T) {
+	idl := MustParseIdl("svc.idl", []byte(`
+interface Users {
+	get(id string) string
+}
+`))
+	code := string(idl.GenerateGo("svc", "", false, IncludeContextBoth)["svc"])
+
+	for _, expected := range []string{
+		"\t_err := barrister.CallInto(context.Background(), _p.client, _p.idl, \"Users.get\", &_res, id)\n",
+		// the WithContext variant calls the same IDL function
+		"\t_err := barrister.CallInto(ctx, _p.client, _p.idl, \"Users.get\", &_res, id)\n",
+		"\t_svr.AddHandler(\"Users\", users)\n",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Generated code does not contain %q:\n%s", expected, code)
+		}
+	}
+}
+
+func TestGenerateGoMocks(t *testing.T) {
+	idl := MustParseIdl("svc.idl", []byte(`
+interface Users {
+	get(id string) string
+}
+`))
+	opts := GenerateOptions{DefaultPkgName: "svc", IncludeContext: IncludeContextBoth}
+	code, err := idl.GenerateGoOptions(opts)
+	Equals(t, err, nil)
+	if strings.Contains(string(code["svc"]), "UsersMock") {
+		t.Errorf("Mocks generated without GenerateOptions.Mocks")
+	}
+
+	opts.Mocks = true
+	code, err = idl.GenerateGoOptions(opts)
+	Equals(t, err, nil)
+	for _, expected := range []string{
+		"\tGetFunc func(id string) (string, error)\n",
+		"\tGetFunc func(ctx context.Context, id string) (string, error)\n",
+		"type UsersMockGetCall struct {\n\tID string\n}",
+		"type UsersWithContextMockGetCall struct {\n\tCtx context.Context\n\tID  string\n}",
+		"func (_m *UsersMock) GetCallCount() int {",
+		"func NewUsersWithContextFakeClient(impl UsersWithContext) barrister.ClientContext {",
+		"\t_svr.AddHandler(\"Users\", impl)\n",
+	} {
+		if !strings.Contains(string(code["svc"]), expected) {
+			t.Errorf("Generated code does not contain %q:\n%s", expected, code["svc"])
+		}
+	}
+}
+
+func TestGenerateGoMocksUpperCaseFunction(t *testing.T) {
+	idl := MustParseIdl("calc.idl", []byte(`
+interface Calc {
+	Add(a int, b int) int
+}
+`))
+	opts := GenerateOptions{DefaultPkgName: "calc", IncludeContext: IncludeContextBoth, Mocks: true}
+	code, err := idl.GenerateGoOptions(opts)
+	Equals(t, err, nil)
+
+	// type check the generated code with empty imports, and fail on
+	// anything but errors caused by the imports being empty
+	fset := gotoken.NewFileSet()
+	file, err := parser.ParseFile(fset, "calc.go", code["calc"], 0)
+	Equals(t, err, nil)
+	conf := types.Config{
+		Importer: emptyImporter{},
+		Error: func(err error) {
+			msg := err.Error()
+			if !strings.Contains(msg, "undefined: ") && !strings.Contains(msg, "imported and not used") {
+				t.Error(err)
+			}
+		},
+	}
+	conf.Check("calc", fset, []*ast.File{file}, nil)
+
+	if !strings.Contains(string(code["calc"]), "func (_m *CalcMock) AddCalls() []CalcMockAddCall {") {
+		t.Errorf("Generated code has no AddCalls method:\n%s", code["calc"])
+	}
+}
+
+// emptyImporter imports every package as an empty package
+type emptyImporter struct{}
+
+func (emptyImporter) Import(path string) (*types.Package, error) {
+	pkg := types.NewPackage(path, filepath.Base(path))
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+func TestGenerateGoNameCollisions(t *testing.T) {
+	for i, tc := range []struct {
+		idl  string
+		opts GenerateOptions
+		err  string
+	}{
+		{
+			"struct User { user_id string\n userId string }",
+			GenerateOptions{},
+			"barrister: User: field user_id and field userId have the same Go name: UserID",
+		},
+		{
+			"struct Base { user_id string }\nstruct User extends Base { userId string }",
+			GenerateOptions{},
+			"barrister: User: field user_id and field userId have the same Go name: UserID",
+		},
+		{
+			"struct Base { a string }\nstruct User extends Base { base string }",
+			GenerateOptions{},
+			"barrister: User: embedded struct Base and field base have the same Go name: Base",
+		},
+		{
+			"interface Users { get(ctx string) string }",
+			GenerateOptions{IncludeContext: IncludeContextYes},
+			"barrister: Users.get: context param and param ctx have the same Go name: ctx",
+		},
+		{
+			"interface Users { get(Ctx string) string }",
+			GenerateOptions{IncludeContext: IncludeContextBoth, Mocks: true},
+			"barrister: Users.get: context param and param Ctx have the same Go name: Ctx",
+		},
+		{
+			"interface Users { get(user_id string, userId string) string }",
+			GenerateOptions{Mocks: true},
+			"barrister: Users.get: param user_id and param userId have the same Go name: UserID",
+		},
+	} {
+		idl := MustParseIdl("svc.idl", []byte(tc.idl))
+		tc.opts.DefaultPkgName = "svc"
+		_, err := idl.GenerateGoOptions(tc.opts)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("%d: expected error %q, got %v", i, tc.err, err)
+		}
+	}
+
+	// names that only collide in mocks or context methods are allowed without them
+	idl := MustParseIdl("svc.idl", []byte("interface Users { get(ctx string, user_id string, userId string) string }"))
+	_, err := idl.GenerateGoOptions(GenerateOptions{DefaultPkgName: "svc"})
+	Equals(t, err, nil)
+}
+
+func TestGenerateGoEnumMethods(t *testing.T) {
+	idl := MustParseIdl("svc.idl", []byte(`
+enum Status { ok err }
+`))
+	code := string(idl.GenerateGo("svc", "", false, IncludeContextNo)["svc"])
+
+	for _, expected := range []string{
+		"import (\n\t\"encoding/json\"\n",
+		"func StatusValues() []Status {\n\treturn []Status{StatusOk, StatusErr}\n}",
+		"func ParseStatus(s string) (Status, error) {",
+		"func (_e Status) IsValid() bool {\n\tswitch _e {\n\tcase StatusOk, StatusErr:\n\t\treturn true\n",
+		"func (_e Status) String() string {\n\treturn string(_e)\n}",
+		"func (_e *Status) UnmarshalJSON(b []byte) error {",
+		"\t_v, _err := ParseStatus(_s)\n",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Generated code does not contain %q:\n%s", expected, code)
+		}
+	}
+}
+
+func TestGenerateGoValidate(t *testing.T) {
+	idl := MustParseIdl("svc.idl", []byte(`
+enum Status { ok err }
+struct Base { status Status }
+struct User extends Base {
+	tags []Status
+	home User [optional]
+}
+`))
+	code := string(idl.GenerateGo("svc", "", false, IncludeContextNo)["svc"])
+
+	for _, expected := range []string{
+		"func (_e Status) Validate() error {\n\tif _e.IsValid() {\n",
+		"func (_s User) Validate() error {\n\tif _err := _s.Base.Validate(); _err != nil {\n\t\treturn barrister.ValidationErrorAt(\"User\", _err)\n",
+		"\tif _s.Tags == nil {\n\t\treturn &barrister.ValidationError{Path: \"User.tags\", Msg: \"required field is nil\"}\n",
+		"\t\t\treturn barrister.ValidationErrorAtIndex(\"User.tags\", _i, _err)\n",
+		"\tif _s.Home != nil {\n\t\tif _err := _s.Home.Validate(); _err != nil {\n",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Generated code does not contain %q:\n%s", expected, code)
+		}
+	}
+}
+
+func TestGenerateGoTypeMapping(t *testing.T) {
+	idl := MustParseIdl("svc.idl", []byte(`
+struct Uuid { value string }
+struct User {
+	id    Uuid
+	age   int
+	score float [optional]
+	tags  []Uuid
+}
+interface UserService {
+	get(id Uuid, limit int) User
+}
+`))
+	code, err := idl.GenerateGoOptions(GenerateOptions{
+		DefaultPkgName: "svc",
+		Types: &TypeMapping{
+			Types:  map[string]string{"Uuid": "github.com/google/uuid.UUID", "float": "float32"},
+			Fields: map[string]string{"User.age": "int32", "UserService.get.limit": "int16"},
+		},
+	})
+	Equals(t, err, nil)
+	src := string(code["svc"])
+
+	for _, expected := range []string{
+		"\t\"github.com/google/uuid\"\n",
+		"\tID    uuid.UUID   `json:\"id\"`\n",
+		"\tAge   int32       `json:\"age\"`\n",
+		"\tScore float32     `json:\"score,omitempty\"`\n",
+		"\tTags  []uuid.UUID `json:\"tags\"`\n",
+		"\tGet(id uuid.UUID, limit int16) (User, error)\n",
+	} {
+		if !strings.Contains(src, expected) {
+			t.Errorf("Generated code does not contain %q:\n%s", expected, src)
+		}
+	}
+	if strings.Contains(src, "type Uuid struct") {
+		t.Errorf("Generated code contains mapped struct:\n%s", src)
+	}
+
+	_, err = idl.GenerateGoOptions(GenerateOptions{
+		DefaultPkgName: "svc",
+		Types:          &TypeMapping{Fields: map[string]string{"User.nope": "int32"}},
+	})
+	Equals(t, err.Error(), "barrister: type mapping: IDL has no struct field or param: User.nope")
+}
+
+func TestGenerateGoTemplates(t *testing.T) {
+	idl := MustParseIdl("svc.idl", []byte(`
+namespace app
+struct User {
+	name string
+}
+`))
+	templates := fstest.MapFS{
+		"struct.tmpl": &fstest.MapFile{Data: []byte(`
+{{define "struct" -}}
+type {{typeName .Name}} struct {
+{{- range .Fields}}
+	{{fieldName .Name}} {{fieldType $ .}} ` + "`json:\"{{.Name}}\" db:\"{{.Name}}\"`" + `
+{{- end}}
+}
+{{end}}`)},
+		"names.go.tmpl": &fstest.MapFile{Data: []byte(`package {{.Package}}
+
+var StructNames = []string{ {{- range .Structs}}{{printf "%q" .Name}},{{end}} }
+`)},
+		"empty.txt.tmpl": &fstest.MapFile{Data: []byte(`{{range .Enums}}{{.Name}}{{end}}`)},
+	}
+
+	files, err := idl.GenerateGoFiles(GenerateOptions{DefaultPkgName: "svc", Templates: templates})
+	Equals(t, err, nil)
+	Equals(t, len(files), 2)
+
+	code := string(files["app/app.go"])
+	if !strings.Contains(code, "type User struct {\n\tName string `json:\"name\" db:\"name\"`\n}\n") {
+		t.Errorf("Redefined struct template not used:\n%s", code)
+	}
+	Equals(t, string(files["app/names.go"]), "package app\n\nvar StructNames = []string{\"app.User\"}\n")
+
+	// GenerateGoOptions only returns the package files
+	pkgs, err := idl.GenerateGoOptions(GenerateOptions{DefaultPkgName: "svc", Templates: templates})
+	Equals(t, err, nil)
+	Equals(t, string(pkgs["app"]), code)
+	Equals(t, len(pkgs), 1)
+
+	templates["struct.tmpl"] = &fstest.MapFile{Data: []byte(`{{define "struct"}}{{.Nope}}{{end}}`)}
+	_, err = idl.GenerateGoFiles(GenerateOptions{DefaultPkgName: "svc", Templates: templates})
+	if err == nil || !strings.HasPrefix(err.Error(), "barrister: template: ") {
+		t.Errorf("Expected template error, got: %v", err)
+	}
+}
+
+func TestGenerateGoPackages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "barrister")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "inc.idl"), []byte("namespace inc\nenum Status { ok err }\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "common.idl"), []byte("namespace common\nstruct Page { size int }\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idl, err := ParseIdl(filepath.Join(dir, "svc.idl"), []byte(`
+import "inc.idl"
+import "common.idl"
+struct Response {
+	status inc.Status
+	page   common.Page
+}
+interface Svc {
+	get() Response
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := idl.GenerateGoOptions(GenerateOptions{DefaultPkgName: "svc", BaseImport: "example.com/gen"})
+	Equals(t, err, nil)
+	for _, expected := range []string{
+		"\t\"example.com/gen/common\"\n",
+		"\t\"example.com/gen/inc\"\n",
+		"\t\"github.com/coopernurse/barrister-go\"\n",
+	} {
+		if !strings.Contains(string(code["svc"]), expected) {
+			t.Errorf("Generated code does not contain %q:\n%s", expected, code["svc"])
+		}
+	}
+
+	code, err = idl.GenerateGoOptions(GenerateOptions{
+		DefaultPkgName: "svc",
+		BaseImport:     "example.com/gen/",
+		Packages:       map[string]string{"inc": "example.com/shared/incpb", "common": "example.com/common/v2;common"},
+		RuntimeImport:  "github.com/cabify/barrister-go",
+	})
+	Equals(t, err, nil)
+	for _, expected := range []string{
+		"\t\"example.com/common/v2\"\n",
+		"\tinc \"example.com/shared/incpb\"\n",
+		"\t\"github.com/cabify/barrister-go\"\n",
+		"\tStatus inc.Status  `json:\"status\"`\n",
+	} {
+		if !strings.Contains(string(code["svc"]), expected) {
+			t.Errorf("Generated code does not contain %q:\n%s", expected, code["svc"])
+		}
+	}
+	if _, ok := code["inc"]; ok {
+		t.Errorf("Code generated for namespace in Packages:\n%s", code["inc"])
+	}
+	if _, ok := code["common"]; ok {
+		t.Errorf("Code generated for namespace in Packages:\n%s", code["common"])
+	}
+
+	_, err = idl.GenerateGoOptions(GenerateOptions{DefaultPkgName: "svc", Packages: map[string]string{"nope": "example.com/nope"}})
+	Equals(t, err.Error(), "barrister: packages: IDL has no namespace: nope")
+	_, err = idl.GenerateGoOptions(GenerateOptions{DefaultPkgName: "svc", Packages: map[string]string{"inc": "example.com/barrister-go"}})
+	Equals(t, err.Error(), "barrister: packages: invalid package for namespace inc: example.com/barrister-go")
+}
+
+func TestGenerateGoIsReproducible(t *testing.T) {
+	src := []byte(`
+namespace app
+
+enum Zeta { z }
+enum Alpha { a }
+enum Mid { m }
+
+struct Item {
+	zeta  Zeta
+	alpha Alpha
+}
+`)
+	nsDir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(nsDir, "app.idl"), src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	main := []byte(`
+import "app.idl"
+
+enum Local { b a }
+
+interface Svc {
+	get(m app.Mid) app.Item
+}
+`)
+	generate := func() map[string][]byte {
+		idl, err := ParseIdl(filepath.Join(nsDir, "main.idl"), main)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, err := idl.GenerateGoSource("svc", "example.com/", false, IncludeContextNo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	first := generate()
+	Equals(t, len(first), 2)
+	for i := 0; i < 10; i++ {
+		DeepEquals(t, generate(), first)
+	}
+
+	for pkg, code := range first {
+		formatted, err := format.Source(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		Equals(t, string(formatted), string(code))
+		if pkg == "app" {
+			zeta := strings.Index(string(code), "type Zeta string")
+			alpha := strings.Index(string(code), "type Alpha string")
+			mid := strings.Index(string(code), "type Mid string")
+			if !(zeta < alpha && alpha < mid) {
+				t.Errorf("Enums not generated in IDL order:\n%s", code)
+			}
+		}
+	}
+}
+
+func TestGenerateGoSourceSyntaxError(t *testing.T) {
+	idl := NewIdl([]IdlJsonElem{
+		{Type: "struct", Name: "bad name", Fields: []Field{{Name: "a", Type: "string"}}},
+		{Type: "meta"},
+	})
+	_, err := idl.GenerateGoSource("bad", "", false, IncludeContextNo)
+	genErr, ok := err.(*GenerateError)
+	if !ok {
+		t.Fatalf("Expected *GenerateError, got: %v", err)
+	}
+	Equals(t, genErr.Package, "bad")
+	Equals(t, genErr.Line, "type Bad name struct {")
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// maxDiffEdits is the largest number of added and removed lines diffLines
+// looks for.  Its trace grows with the square of the number of edits, so
+// files that differ by more lines are shown as a replacement of every line.
+const maxDiffEdits = 1000
+
+type diffOp struct {
+	// ' ' for an unchanged line, '-' for a removed line, '+' for an added line
+	kind byte
+	line string
+}
+
+// unifiedDiff returns the differences between a and b in unified diff format,
+// or an empty string if they are equal
+func unifiedDiff(nameA string, nameB string, a []byte, b []byte) string {
+	if bytes.Equal(a, b) {
+		return ""
+	}
+
+	ops := diffLines(splitLines(a), splitLines(b))
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "--- %s\n+++ %s\n", nameA, nameB)
+
+	// line numbers in a and b of the op at index i
+	lineA, lineB := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, op := range ops {
+		lineA[i+1], lineB[i+1] = lineA[i], lineB[i]
+		if op.kind != '+' {
+			lineA[i+1]++
+		}
+		if op.kind != '-' {
+			lineB[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// extend the hunk until the next change is more than 2*diffContext lines away
+		start := maxInt(0, i-diffContext)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContext {
+				end = minInt(len(ops), end+diffContext)
+				break
+			}
+			end = next
+		}
+
+		fmt.Fprintf(out, "@@ -%s +%s @@\n",
+			hunkRange(lineA[start], lineA[end]), hunkRange(lineB[start], lineB[end]))
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(out, "%c%s\n", op.kind, op.line)
+		}
+		i = end
+	}
+
+	return out.String()
+}
+
+// hunkRange formats the lines after line number from, up to and including to
+func hunkRange(from int, to int) string {
+	if to-from == 1 {
+		return fmt.Sprintf("%d", to)
+	}
+	if to == from {
+		return fmt.Sprintf("%d,0", from)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
+
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+// diffLines returns the shortest edit script that turns a into b, computed
+// with the Myers diff algorithm, or a script that removes all of a and adds
+// all of b if it needs more than maxDiffEdits edits
+func diffLines(a []string, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+
+	// trace holds v[-d-1:d+2] as it was at the start of each round d, the
+	// only part of v that is read when backtracking from round d
+	trace := [][]int{}
+
+search:
+	for d := 0; d <= max; d++ {
+		if d > maxDiffEdits {
+			return replaceLines(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset]
+			} else {
+				x = v[k-1+offset] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+offset] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	ops := []diffOp{}
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v, offset := trace[d], d+1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+offset]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{'+', b[y-1]})
+				y--
+			} else {
+				ops = append(ops, diffOp{'-', a[x-1]})
+				x--
+			}
+		}
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// replaceLines returns an edit script that removes all of a and adds all of b
+func replaceLines(a []string, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{'+', line})
+	}
+	return ops
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n"
+
+	expected := `--- old.go
++++ new.go
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -13,3 +13,4 @@
+ 13
+ 14
+ 15
++16
+`
+	actual := unifiedDiff("old.go", "new.go", []byte(a), []byte(b))
+	if actual != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, actual)
+	}
+
+	if diff := unifiedDiff("a", "b", []byte(a), []byte(a)); diff != "" {
+		t.Errorf("Expected no diff, got:\n%s", diff)
+	}
+
+	expected = "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+	if diff := unifiedDiff("a", "b", nil, []byte("x\ny\n")); diff != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, diff)
+	}
+}
+
+func TestUnifiedDiffManyEdits(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < maxDiffEdits; i++ {
+		fmt.Fprintf(&a, "a%d\n", i)
+		fmt.Fprintf(&b, "b%d\n", i)
+	}
+
+	diff := unifiedDiff("a", "b", []byte(a.String()), []byte(b.String()))
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	expected := fmt.Sprintf("@@ -1,%d +1,%d @@", maxDiffEdits, maxDiffEdits)
+	if len(lines) != 3+2*maxDiffEdits || lines[2] != expected {
+		t.Errorf("Expected one hunk replacing every line, got:\n%s", diff)
+	}
+	if lines[3] != "-a0" || lines[3+maxDiffEdits] != "+b0" {
+		t.Errorf("Expected all lines removed, then added, got:\n%s", diff)
+	}
+}
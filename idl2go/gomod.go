@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// moduleImportPath returns the import path of dir, based on the module path
+// in the go.mod file in dir or its nearest parent that has one.  It returns
+// "" if there is no go.mod file.
+func moduleImportPath(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for root := dir; ; root = filepath.Dir(root) {
+		gomod := filepath.Join(root, "go.mod")
+		if _, err := os.Stat(gomod); err == nil {
+			modPath, err := readModulePath(gomod)
+			if err != nil {
+				return "", err
+			}
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				return "", err
+			}
+			if rel == "." {
+				return modPath, nil
+			}
+			return modPath + "/" + filepath.ToSlash(rel), nil
+		}
+		if filepath.Dir(root) == root {
+			return "", nil
+		}
+	}
+}
+
+// readModulePath returns the path in the module directive of a go.mod file
+func readModulePath(gomod string) (string, error) {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i > -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		modPath := fields[1]
+		if strings.HasPrefix(modPath, `"`) || strings.HasPrefix(modPath, "`") {
+			modPath, err = strconv.Unquote(modPath)
+			if err != nil {
+				return "", fmt.Errorf("%s: invalid module path: %s", gomod, fields[1])
+			}
+		}
+		return modPath, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s: no module directive", gomod)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModuleImportPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "idl2go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	gen := filepath.Join(dir, "internal", "gen")
+	err = os.MkdirAll(gen, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := moduleImportPath(gen)
+	if err != nil || p != "" {
+		t.Errorf("Expected no import path without go.mod, got: %q %v", p, err)
+	}
+
+	gomod := "// comment\nmodule \"example.com/svc\" // the module\n\ngo 1.21\n"
+	err = ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for d, expected := range map[string]string{
+		dir: "example.com/svc",
+		gen: "example.com/svc/internal/gen",
+	} {
+		p, err = moduleImportPath(d)
+		if err != nil || p != expected {
+			t.Errorf("Expected %s for %s, got: %q %v", expected, d, p, err)
+		}
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("go 1.21\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = moduleImportPath(gen)
+	if err == nil {
+		t.Errorf("Expected error for go.mod without module directive")
+	}
+}
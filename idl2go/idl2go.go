@@ -1,13 +1,19 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/coopernurse/barrister-go"
+	"go/parser"
+	"go/token"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"unicode"
 )
 
 func main() {
@@ -19,27 +25,61 @@ func main() {
 	var tostdout bool
 	var fromstdin bool
 	var includeContextFlag string
+	var checksumFlag string
+	var check bool
+	var mocks bool
+	var typesFile string
+	var templatesDir string
+	var plugin string
+	var runtimeImport string
+	var configFile string
+	packages := packageFlag{}
 
 	flag.StringVar(&outdir, "d", ".", "Base directory to write generated .go files to")
 	flag.StringVar(&defaultPkgName, "p", "", "Package name to write to generated Go file")
-	flag.StringVar(&baseImport, "b", "", "Base import path for imported namespaces")
+	flag.StringVar(&baseImport, "b", "", "Base import path for imported namespaces (default: the import path of -d, if it is in a Go module)")
+	flag.Var(packages, "pkg", `Go package of an IDL namespace, as "namespace=importpath", optionally followed by ";name" if the package name is not the last element of the path. No code is generated for the namespace. May be repeated`)
+	flag.StringVar(&runtimeImport, "runtime", barrister.DefaultRuntimeImport, "Import path of the barrister package used by generated code")
 	flag.BoolVar(&optionalToPtr, "n", false, "If true, optional IDL fields will be generated as Go pointers")
 	flag.BoolVar(&quiet, "q", false, "Enable quiet mode (no output)")
 	flag.BoolVar(&tostdout, "s", false, "Write .go file to STDOUT (implies -q)")
-	flag.BoolVar(&fromstdin, "i", false, "Read IDL JSON from STDIN")
+	flag.BoolVar(&fromstdin, "i", false, "Read IDL JSON or .idl source from STDIN")
 	flag.StringVar(&includeContextFlag, "context", "no", `Whether to add a "context".Context parameter to methods. Valid values: "no"; "yes"; "both", which will create two interfaces`)
+	flag.StringVar(&checksumFlag, "checksum", "ignore", `What to do if the checksum in IDL JSON does not match its contents. Valid values: "ignore"; "warn"; "fail". Checksums written by the Python translator are not reproduced, so they always mismatch`)
+	flag.BoolVar(&check, "check", false, "Compare the generated code with the .go files under -d instead of writing them. Prints only a diff, and exits 1 if any file is out of date")
+	flag.BoolVar(&mocks, "mocks", false, "Also generate a mock and an in-memory fake client for each interface, for use in tests")
+	flag.StringVar(&typesFile, "types", "", `JSON file that maps IDL types and fields to existing Go types, e.g. {"types": {"Uuid": "github.com/google/uuid.UUID"}, "fields": {"User.age": "int32"}}`)
+	flag.StringVar(&templatesDir, "templates", "", `Directory of *.tmpl files that redefine the default templates, e.g. {{define "struct"}}...{{end}}. Files named like "extra.go.tmpl" generate an extra file in each package`)
+	flag.StringVar(&configFile, "config", "", "Project config file listing IDL files to generate code for, with the options for each, instead of a single IDL file. See the README for its format")
+	flag.StringVar(&plugin, "plugin", "", "Command, with optional args, of a plugin to generate code with instead of the Go generator. The plugin reads a JSON request with the IDL from STDIN and writes the generated files to STDOUT")
 	flag.Parse()
 
-	if !fromstdin && flag.NArg() != 1 {
-		fmt.Fprintf(os.Stderr, "Usage: idl2go jsonfile\n")
+	if configFile == "" && !fromstdin && flag.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: idl2go [jsonfile | idlfile]\n       idl2go -config project.json\n")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	if tostdout {
+	// the generated code or diff is the only output
+	if tostdout || check {
 		quiet = true
 	}
 
+	if checksumFlag != "warn" && checksumFlag != "fail" && checksumFlag != "ignore" {
+		fmt.Fprintf(os.Stderr, `Invalid value %q for flag "checksum". Valid values: "warn", "fail", "ignore".`+"\n", checksumFlag)
+		os.Exit(1)
+	}
+
+	if configFile != "" {
+		files, err := generateProject(configFile, checksumFlag, quiet)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating code from %s: %s\n", configFile, err)
+			os.Exit(1)
+		}
+		outputFiles(check, quiet, tostdout, filepath.Dir(configFile), configFile, files)
+		return
+	}
+
 	jsonFile := flag.Arg(0)
 	baseName := filepath.Base(jsonFile)
 	pos := strings.LastIndex(baseName, ".")
@@ -59,51 +99,179 @@ func main() {
 		}
 	}
 
-	var includeContext barrister.IncludeContext
-	switch includeContextFlag {
-	case "yes":
-		includeContext = barrister.IncludeContextYes
-	case "no":
-		includeContext = barrister.IncludeContextNo
-	case "both":
-		includeContext = barrister.IncludeContextBoth
-	default:
+	includeContext, ok := includeContexts[includeContextFlag]
+	if !ok {
 		fmt.Fprintf(os.Stderr, `Invalid value %q for flag "context". Valid values: "yes", "no", "both".`+"\n", includeContextFlag)
 		os.Exit(1)
 	}
 
+	from := jsonFile
+	if fromstdin {
+		from = "STDIN"
+	}
+
 	idl, err := parseIdl(fromstdin, jsonFile)
 	if err != nil {
-		from := jsonFile
-		if fromstdin {
-			from = "STDIN"
+		fmt.Fprint(os.Stderr, loadError(from, err))
+		os.Exit(1)
+	}
+
+	if checksumFlag != "ignore" {
+		err = idl.VerifyChecksum()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Checksum mismatch in %s, was the IDL JSON edited by hand? %s\n", from, err)
+			if checksumFlag == "fail" {
+				os.Exit(1)
+			}
+		}
+	}
+
+	var types *barrister.TypeMapping
+	if typesFile != "" {
+		types, err = loadTypeMapping(typesFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading type mapping from %s: %s\n", typesFile, err)
+			os.Exit(1)
+		}
+	}
+
+	if baseImport == "" {
+		baseImport, err = moduleImportPath(outdir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading go.mod: %s\n", err)
+			os.Exit(1)
+		}
+		if baseImport != "" && !quiet {
+			fmt.Println("Using base import path from go.mod:", baseImport)
+		}
+	}
+
+	opts := barrister.GenerateOptions{
+		DefaultPkgName: defaultPkgName,
+		BaseImport:     baseImport,
+		OptionalToPtr:  optionalToPtr,
+		IncludeContext: includeContext,
+		Mocks:          mocks,
+		Types:          types,
+		Packages:       packages,
+		RuntimeImport:  runtimeImport,
+	}
+
+	var files map[string][]byte
+	if plugin != "" {
+		files, err = runPlugin(plugin, &barrister.PluginRequest{Idl: idl, Options: opts})
+	} else {
+		if templatesDir != "" {
+			opts.Templates = os.DirFS(templatesDir)
+		}
+		files, err = idl.GenerateGoFiles(opts)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating code from %s: %s\n", from, err)
+		os.Exit(1)
+	}
+
+	outputFiles(check, quiet, tostdout, outdir, from, files)
+}
+
+// outputFiles writes the generated files, which are keyed by path relative to
+// outdir, or with check, compares them with the files on disk
+func outputFiles(check bool, quiet bool, tostdout bool, outdir string, from string, files map[string][]byte) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if check {
+		stale := false
+		for _, name := range names {
+			if !checkCode(outdir, name, files[name]) {
+				stale = true
+			}
 		}
-		fmt.Fprintf(os.Stderr, "Error loading IDL from %s: %s\n", from, err)
+		if stale {
+			fmt.Fprintf(os.Stderr, "Generated code in %s is out of date with %s\n", outdir, from)
+			os.Exit(1)
+		}
+		return
+	}
+
+	for _, name := range names {
+		writeCode(quiet, tostdout, outdir, name, files[name])
+	}
+}
+
+// includeContexts are the valid values of -context
+var includeContexts = map[string]barrister.IncludeContext{
+	"yes":  barrister.IncludeContextYes,
+	"no":   barrister.IncludeContextNo,
+	"both": barrister.IncludeContextBoth,
+}
+
+// loadError returns the message to print for an error loading IDL from the
+// given file, which lists each problem if the IDL is invalid
+func loadError(from string, err error) string {
+	idlErrs, ok := err.(barrister.IdlErrors)
+	if !ok {
+		return fmt.Sprintf("Error loading IDL from %s: %s\n", from, err)
+	}
+	msg := fmt.Sprintf("Invalid IDL in %s:\n", from)
+	for _, e := range idlErrs {
+		msg += fmt.Sprintf("  %s: %s\n", e.Location(), e.Msg)
+	}
+	return msg
+}
+
+// codeFile returns the path of the generated file name, which is relative to
+// outdir and separated by "/"
+func codeFile(outdir string, name string) string {
+	return filepath.Join(outdir, filepath.FromSlash(name))
+}
+
+// checkCode prints a unified diff if the file on disk for name differs from
+// code, and returns true if they are the same
+func checkCode(outdir string, name string, code []byte) bool {
+	outfile := codeFile(outdir, name)
+	existing, err := ioutil.ReadFile(outfile)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Error reading file %s: %s\n", outfile, err)
 		os.Exit(1)
 	}
 
-	pkgNameToGoCode := idl.GenerateGo(defaultPkgName, baseImport, optionalToPtr, includeContext)
-	for pkg, code := range pkgNameToGoCode {
-		writeCode(quiet, tostdout, outdir, pkg, code)
+	diff := unifiedDiff(outfile, outfile+" (generated)", existing, code)
+	if diff == "" {
+		return true
 	}
+	if os.IsNotExist(err) {
+		fmt.Printf("%s does not exist\n", outfile)
+	}
+	fmt.Print(diff)
+	return false
 }
 
-func writeCode(quiet bool, tostdout bool, outdir string, pkg string, code []byte) {
+func writeCode(quiet bool, tostdout bool, outdir string, name string, code []byte) {
 
 	if tostdout {
 		fmt.Println(string(code))
 	} else {
 
-		dir := filepath.Join(outdir, pkg)
+		outfile := codeFile(outdir, name)
+		dir := filepath.Dir(outfile)
 		err := os.MkdirAll(dir, 0755)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error creating dir %s: %s\n", dir, err)
 			os.Exit(1)
 		}
-		outfile := filepath.Join(dir, fmt.Sprintf("%s.go", pkg))
 
 		if !quiet {
-			fmt.Printf("Generating %s as package %s\n", outfile, pkg)
+			dir := path.Base(path.Dir(name))
+			f, err := parser.ParseFile(token.NewFileSet(), "", code, parser.PackageClauseOnly)
+			if path.Base(name) == dir+".go" && err == nil {
+				fmt.Printf("Generating %s as package %s\n", outfile, f.Name.Name)
+			} else {
+				fmt.Printf("Generating %s\n", outfile)
+			}
 		}
 
 		err = ioutil.WriteFile(outfile, code, 0644)
@@ -114,14 +282,56 @@ func writeCode(quiet bool, tostdout bool, outdir string, pkg string, code []byte
 	}
 }
 
+// parseIdl loads the IDL from STDIN or the given file.  Files with a .idl
+// extension are parsed as IDL source, other files as IDL JSON.  Input from
+// STDIN is treated as JSON if it starts with '['.
 func parseIdl(fromstdin bool, jsonFile string) (*barrister.Idl, error) {
 	if fromstdin {
-		jsonData, err := ioutil.ReadAll(os.Stdin)
+		data, err := ioutil.ReadAll(os.Stdin)
 		if err != nil {
 			return nil, err
 		}
-		return barrister.ParseIdlJson(jsonData)
+		trimmed := strings.TrimLeftFunc(string(data), unicode.IsSpace)
+		if strings.HasPrefix(trimmed, "[") {
+			return barrister.ParseIdlJson(data)
+		}
+		return barrister.ParseIdl("STDIN", data)
+	}
+
+	return barrister.LoadIdlFile(jsonFile)
+}
+
+// packageFlag holds the namespace packages given with -pkg
+type packageFlag map[string]string
+
+func (f packageFlag) String() string {
+	pkgs := make([]string, 0, len(f))
+	for ns, p := range f {
+		pkgs = append(pkgs, ns+"="+p)
 	}
+	sort.Strings(pkgs)
+	return strings.Join(pkgs, " ")
+}
+
+func (f packageFlag) Set(s string) error {
+	pos := strings.Index(s, "=")
+	if pos < 1 || pos == len(s)-1 {
+		return fmt.Errorf(`expected "namespace=importpath" but got %q`, s)
+	}
+	f[s[:pos]] = s[pos+1:]
+	return nil
+}
 
-	return barrister.ParseIdlJsonFile(jsonFile)
+// loadTypeMapping reads a barrister.TypeMapping from the given JSON file
+func loadTypeMapping(fname string) (*barrister.TypeMapping, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	types := &barrister.TypeMapping{}
+	err = json.Unmarshal(data, types)
+	if err != nil {
+		return nil, err
+	}
+	return types, nil
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/coopernurse/barrister-go"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runPlugin runs the plugin command, which may include args, with req as
+// JSON on its STDIN, and returns the files in the barrister.PluginResponse
+// it writes to STDOUT, keyed by path.  The plugin's STDERR is passed through.
+func runPlugin(command string, req *barrister.PluginRequest) (map[string][]byte, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no plugin command")
+	}
+
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &bytes.Buffer{}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %s", args[0], err)
+	}
+
+	resp := barrister.PluginResponse{}
+	err = json.Unmarshal(out.Bytes(), &resp)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s wrote an invalid response: %s", args[0], err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", args[0], resp.Error)
+	}
+
+	files := make(map[string][]byte, len(resp.Files))
+	for _, f := range resp.Files {
+		if !fs.ValidPath(f.Name) || f.Name == "." {
+			return nil, fmt.Errorf("plugin %s returned an invalid file name: %q", args[0], f.Name)
+		}
+		files[f.Name] = []byte(f.Content)
+	}
+	return files, nil
+}
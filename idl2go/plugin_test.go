@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"github.com/coopernurse/barrister-go"
+	"os"
+	"strings"
+	"testing"
+)
+
+// when IDL2GO_TEST_PLUGIN is set, the test binary acts as the plugin
+func TestRunPlugin(t *testing.T) {
+	switch os.Getenv("IDL2GO_TEST_PLUGIN") {
+	case "ok":
+		barrister.RunPlugin(os.Stdin, os.Stdout, func(req *barrister.PluginRequest) (map[string][]byte, error) {
+			return map[string][]byte{req.Options.DefaultPkgName + "/names.txt": []byte(strings.Join(req.Idl.Namespaces(), ","))}, nil
+		})
+		os.Exit(0)
+	case "bad-name":
+		fmt.Println(`{"files":[{"name":"../x.go","content":""}]}`)
+		os.Exit(0)
+	case "fail":
+		barrister.RunPlugin(os.Stdin, os.Stdout, func(req *barrister.PluginRequest) (map[string][]byte, error) {
+			return nil, fmt.Errorf("no thanks")
+		})
+		os.Exit(0)
+	}
+
+	idl := barrister.MustParseIdl("svc.idl", []byte(`
+namespace app
+enum Status { ok }
+`))
+	req := &barrister.PluginRequest{Idl: idl, Options: barrister.GenerateOptions{DefaultPkgName: "svc"}}
+	command := os.Args[0] + " -test.run=TestRunPlugin"
+
+	t.Setenv("IDL2GO_TEST_PLUGIN", "ok")
+	files, err := runPlugin(command, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || string(files["svc/names.txt"]) != "app" {
+		t.Errorf("Unexpected files: %v", files)
+	}
+
+	for mode, expected := range map[string]string{
+		"bad-name": `returned an invalid file name: "../x.go"`,
+		"fail":     ": no thanks",
+	} {
+		t.Setenv("IDL2GO_TEST_PLUGIN", mode)
+		_, err = runPlugin(command, req)
+		if err == nil || !strings.HasSuffix(err.Error(), expected) {
+			t.Errorf("%s: expected error ending in %q, got: %v", mode, expected, err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/coopernurse/barrister-go"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// project is read from the JSON project config file given with -config, and
+// lists IDL files to generate code for in one run.  Paths are relative to the
+// directory of the config file.
+type project struct {
+	// Base import path for imported namespaces, as for -b.  Defaults to the
+	// import path of the dir of each IDL, if it is in a Go module.
+	BaseImport string `json:"base_import"`
+
+	// Import path of the barrister package, as for -runtime
+	RuntimeImport string `json:"runtime_import"`
+
+	// Go packages of IDL namespaces, as for -pkg, shared by all IDL files.
+	// Each IDL file only uses the namespaces it has.
+	Packages map[string]string `json:"packages"`
+
+	// Directory of templates, as for -templates
+	Templates string `json:"templates"`
+
+	Idls []projectIdl `json:"idls"`
+}
+
+// projectIdl holds the options for one IDL file in a project
+type projectIdl struct {
+	// IDL JSON or .idl file
+	Idl string `json:"idl"`
+
+	// Directory to write generated code to, as for -d.  Defaults to the
+	// directory of the config file.
+	Dir string `json:"dir"`
+
+	// Package name, as for -p.  Defaults to the base name of the IDL file.
+	Package string `json:"package"`
+
+	OptionalToPtr bool   `json:"optional_to_ptr"`
+	Context       string `json:"context"`
+	Mocks         bool   `json:"mocks"`
+
+	// JSON file that maps IDL types and fields to Go types, as for -types
+	Types string `json:"types"`
+}
+
+// loadProject reads a project config file.  Unknown keys are an error, so
+// typos are not silently ignored.
+func loadProject(configFile string) (*project, error) {
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	p := &project{}
+	err = dec.Decode(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project config: %s", err)
+	}
+	if len(p.Idls) == 0 {
+		return nil, fmt.Errorf("invalid project config: no idls")
+	}
+	for i, pi := range p.Idls {
+		if pi.Idl == "" {
+			return nil, fmt.Errorf("invalid project config: idls[%d] has no idl", i)
+		}
+		if filepath.IsAbs(pi.Dir) {
+			return nil, fmt.Errorf("invalid project config: dir of %s must be relative to the config file", pi.Idl)
+		}
+		if _, ok := includeContexts[pi.Context]; !ok && pi.Context != "" {
+			return nil, fmt.Errorf(`invalid project config: context of %s is %q. Valid values: "yes", "no", "both"`, pi.Idl, pi.Context)
+		}
+	}
+	return p, nil
+}
+
+// generateProject generates code for each IDL file in the project config
+// file, and returns the files keyed by path relative to the directory of the
+// config file, separated by "/".  Namespaced structs and enums must be
+// defined the same way in every IDL file that has them, and IDL files that
+// generate the same file, e.g. a namespace package shared by IDL files with
+// the same dir, must generate the same code for it.
+func generateProject(configFile string, checksum string, quiet bool) (map[string][]byte, error) {
+	p, err := loadProject(configFile)
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Dir(configFile)
+
+	idls := make([]*barrister.Idl, len(p.Idls))
+	for i, pi := range p.Idls {
+		idlFile := filepath.Join(base, pi.Idl)
+		if !quiet {
+			fmt.Println("Loading IDL from:", idlFile)
+		}
+		idls[i], err = barrister.LoadIdlFile(idlFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s", strings.TrimSuffix(loadError(pi.Idl, err), "\n"))
+		}
+		if checksum != "ignore" {
+			err = idls[i].VerifyChecksum()
+			if err != nil && checksum == "fail" {
+				return nil, fmt.Errorf("checksum mismatch in %s: %s", pi.Idl, err)
+			} else if err != nil {
+				fmt.Fprintf(os.Stderr, "Checksum mismatch in %s, was the IDL JSON edited by hand? %s\n", pi.Idl, err)
+			}
+		}
+	}
+
+	err = checkNamespaces(p.Idls, idls)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+	generatedBy := map[string]string{}
+	for i, pi := range p.Idls {
+		opts, err := p.generateOptions(base, pi, idls[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", pi.Idl, err)
+		}
+		generated, err := idls[i].GenerateGoFiles(opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", pi.Idl, err)
+		}
+		for name, code := range generated {
+			name = path.Join(filepath.ToSlash(pi.Dir), name)
+			if prev, ok := generatedBy[name]; ok && !bytes.Equal(files[name], code) {
+				return nil, fmt.Errorf("%s and %s generate different code for %s", prev, pi.Idl, name)
+			}
+			files[name] = code
+			generatedBy[name] = pi.Idl
+		}
+	}
+	return files, nil
+}
+
+// generateOptions returns the options to generate code for pi with
+func (p *project) generateOptions(base string, pi projectIdl, idl *barrister.Idl) (barrister.GenerateOptions, error) {
+	dir := filepath.Join(base, pi.Dir)
+	opts := barrister.GenerateOptions{
+		DefaultPkgName: pi.Package,
+		BaseImport:     p.BaseImport,
+		OptionalToPtr:  pi.OptionalToPtr,
+		IncludeContext: includeContexts[pi.Context],
+		Mocks:          pi.Mocks,
+		RuntimeImport:  p.RuntimeImport,
+		Packages:       map[string]string{},
+	}
+	if pi.Context == "" {
+		opts.IncludeContext = barrister.IncludeContextNo
+	}
+	if opts.DefaultPkgName == "" {
+		opts.DefaultPkgName = strings.TrimSuffix(filepath.Base(pi.Idl), filepath.Ext(pi.Idl))
+	}
+
+	var err error
+	if opts.BaseImport == "" {
+		opts.BaseImport, err = moduleImportPath(dir)
+		if err != nil {
+			return opts, err
+		}
+	}
+	for _, ns := range idl.Namespaces() {
+		if imp, ok := p.Packages[ns]; ok {
+			opts.Packages[ns] = imp
+		}
+	}
+	if pi.Types != "" {
+		opts.Types, err = loadTypeMapping(filepath.Join(base, pi.Types))
+		if err != nil {
+			return opts, err
+		}
+	}
+	if p.Templates != "" {
+		opts.Templates = os.DirFS(filepath.Join(base, p.Templates))
+	}
+	return opts, nil
+}
+
+// checkNamespaces returns an error listing the namespaced structs and enums
+// that are defined differently by two IDL files.  Comments are ignored.
+func checkNamespaces(pis []projectIdl, idls []*barrister.Idl) error {
+	type definedIn struct {
+		idl string
+		def definition
+	}
+	defined := map[string]definedIn{}
+	collisions := []string{}
+	for i, idl := range idls {
+		for _, el := range idl.Elems() {
+			if (el.Type != "struct" && el.Type != "enum") || !strings.Contains(el.Name, ".") {
+				continue
+			}
+			def := definitionOf(el)
+			prev, ok := defined[el.Name]
+			if !ok {
+				defined[el.Name] = definedIn{pis[i].Idl, def}
+			} else if !reflect.DeepEqual(prev.def, def) {
+				collisions = append(collisions, fmt.Sprintf("%s %s is defined differently in %s and %s",
+					el.Type, el.Name, prev.idl, pis[i].Idl))
+			}
+		}
+	}
+	if len(collisions) > 0 {
+		sort.Strings(collisions)
+		return fmt.Errorf("namespace collision:\n  %s", strings.Join(collisions, "\n  "))
+	}
+	return nil
+}
+
+// definition holds the parts of a struct or enum that affect its JSON
+type definition struct {
+	Type    string
+	Extends string
+	Fields  []barrister.Field
+	Values  []string
+}
+
+func definitionOf(el barrister.IdlJsonElem) definition {
+	def := definition{Type: el.Type, Extends: el.Extends}
+	for _, f := range el.Fields {
+		f.Comment = ""
+		def.Fields = append(def.Fields, f)
+	}
+	for _, v := range el.Values {
+		def.Values = append(def.Values, v.Value)
+	}
+	return def
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeProjectFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGenerateProject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "idl2go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeProjectFiles(t, dir, map[string]string{
+		"go.mod":      "module example.com/app\n",
+		"common.idl":  "namespace common\nstruct Page {\n\tsize int\n\tcursor string [optional]\n}\n",
+		"common2.idl": "namespace common\nstruct Page {\n\tsize string\n}\n",
+		"a.idl":       "import \"common.idl\"\ninterface A {\n\tlist(p common.Page) []string\n}\n",
+		"b.idl":       "import \"common.idl\"\ninterface B {\n\tcount(p common.Page) int\n}\n",
+		"c.idl":       "import \"common2.idl\"\ninterface C {\n\tget(p common.Page) string\n}\n",
+		"idl2go.json": `{
+	"idls": [
+		{"idl": "a.idl", "dir": "gen"},
+		{"idl": "b.idl", "dir": "gen", "package": "bsvc", "context": "yes", "mocks": true}
+	]
+}`,
+	})
+
+	files, err := generateProject(filepath.Join(dir, "idl2go.json"), "warn", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if strings.Join(names, " ") != "gen/a/a.go gen/bsvc/bsvc.go gen/common/common.go" {
+		t.Errorf("Unexpected files: %v", names)
+	}
+	if !strings.Contains(string(files["gen/a/a.go"]), "\t\"example.com/app/gen/common\"\n") {
+		t.Errorf("Import path not derived from go.mod:\n%s", files["gen/a/a.go"])
+	}
+	if !strings.Contains(string(files["gen/bsvc/bsvc.go"]), "Count(ctx context.Context, p common.Page) (int64, error)") {
+		t.Errorf("Per-IDL options not used:\n%s", files["gen/bsvc/bsvc.go"])
+	}
+
+	for config, expected := range map[string]string{
+		`{"idls": [{"idl": "a.idl", "dir": "gen"}, {"idl": "c.idl", "dir": "gen"}]}`:                          "namespace collision:\n  struct common.Page is defined differently in a.idl and c.idl",
+		`{"idls": [{"idl": "a.idl", "dir": "gen"}, {"idl": "b.idl", "dir": "gen", "optional_to_ptr": true}]}`: "a.idl and b.idl generate different code for gen/common/common.go",
+		`{"idls": [{"idl": "a.idl", "dir": "gen", "pkg": "a"}]}`:                                              `invalid project config: json: unknown field "pkg"`,
+		`{"idls": [{"idl": "a.idl", "context": "maybe"}]}`:                                                    `invalid project config: context of a.idl is "maybe". Valid values: "yes", "no", "both"`,
+	} {
+		writeProjectFiles(t, dir, map[string]string{"idl2go.json": config})
+		_, err = generateProject(filepath.Join(dir, "idl2go.json"), "warn", true)
+		if err == nil || err.Error() != expected {
+			t.Errorf("Expected error %q for %s, got: %v", expected, config, err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/coopernurse/barrister-go"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	var out string
+
+	flag.StringVar(&out, "o", "", "File to write the generated TypeScript to (default STDOUT)")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: idl2ts [jsonfile | idlfile]\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	filename := flag.Arg(0)
+	idl, err := barrister.LoadIdlFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading IDL from %s: %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	code := idl.GenerateTypeScript()
+	if out == "" {
+		os.Stdout.Write(code)
+		return
+	}
+
+	err = ioutil.WriteFile(out, code, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file %s: %s\n", out, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote: %s\n", out)
+}
@@ -0,0 +1,208 @@
+package barrister
+
+import (
+	"sort"
+)
+
+// Interface represents an IDL interface and its functions
+type Interface struct {
+	Name      string
+	Comment   string
+	Functions []Function
+}
+
+// Enum represents an IDL enum and its values
+type Enum struct {
+	Name    string
+	Comment string
+	Values  []EnumValue
+}
+
+// TypeKind classifies the type referenced by a Field
+type TypeKind string
+
+const (
+	// KindPrimitive is one of the built in types: string, int, float, bool
+	KindPrimitive TypeKind = "primitive"
+	// KindStruct is a struct declared in the IDL
+	KindStruct TypeKind = "struct"
+	// KindEnum is an enum declared in the IDL
+	KindEnum TypeKind = "enum"
+	// KindUnknown is a type that is not declared in the IDL
+	KindUnknown TypeKind = "unknown"
+)
+
+// AllFields returns the fields of this struct, including the fields
+// inherited from the structs it extends.  Parent fields come first.
+func (s *Struct) AllFields() []Field {
+	return copyFields(s.allFields)
+}
+
+// Elems returns a copy of the elements this Idl was created from,
+// in the order they appear in the IDL JSON document
+func (idl *Idl) Elems() []IdlJsonElem {
+	elems := make([]IdlJsonElem, len(idl.elems))
+	copy(elems, idl.elems)
+	return elems
+}
+
+// Comments returns the values of the file level comment elements,
+// in the order they appear in the IDL
+func (idl *Idl) Comments() []string {
+	comments := []string{}
+	for _, el := range idl.elems {
+		if el.Type == "comment" {
+			comments = append(comments, el.Value)
+		}
+	}
+	return comments
+}
+
+// Interfaces returns the interfaces in the order they appear in the IDL
+func (idl *Idl) Interfaces() []Interface {
+	ifaces := []Interface{}
+	for _, el := range idl.elems {
+		if el.Type == "interface" {
+			ifaces = append(ifaces, Interface{el.Name, el.Comment, copyFunctions(el.Functions)})
+		}
+	}
+	return ifaces
+}
+
+// Interface returns the interface with the given name.  The bool
+// result is false if the IDL has no interface with that name.
+func (idl *Idl) Interface(name string) (Interface, bool) {
+	for _, iface := range idl.Interfaces() {
+		if iface.Name == name {
+			return iface, true
+		}
+	}
+	return Interface{}, false
+}
+
+// Functions returns the functions of the given interface in the order
+// they are declared, or nil if the IDL has no interface with that name.
+func (idl *Idl) Functions(iface string) []Function {
+	funcs, ok := idl.interfaces[iface]
+	if !ok {
+		return nil
+	}
+	return copyFunctions(funcs)
+}
+
+// Structs returns the structs in the order they appear in the IDL
+func (idl *Idl) Structs() []*Struct {
+	structs := []*Struct{}
+	for _, el := range idl.elems {
+		if el.Type == "struct" {
+			s, ok := idl.Struct(el.Name)
+			if ok {
+				structs = append(structs, s)
+			}
+		}
+	}
+	return structs
+}
+
+// Struct returns a copy of the struct with the given name, with its
+// inherited fields resolved.  The bool result is false if the IDL has
+// no struct with that name.
+func (idl *Idl) Struct(name string) (*Struct, bool) {
+	s, ok := idl.structs[name]
+	if !ok {
+		return nil, false
+	}
+	return &Struct{
+		Name:      s.Name,
+		Extends:   s.Extends,
+		Fields:    copyFields(s.Fields),
+		Comment:   s.Comment,
+		allFields: copyFields(s.allFields),
+	}, true
+}
+
+// Enums returns the enums in the order they appear in the IDL
+func (idl *Idl) Enums() []Enum {
+	enums := []Enum{}
+	for _, el := range idl.elems {
+		if el.Type == "enum" {
+			enums = append(enums, Enum{el.Name, el.Comment, copyEnumValues(el.Values)})
+		}
+	}
+	return enums
+}
+
+// Enum returns the enum with the given name.  The bool result is false
+// if the IDL has no enum with that name.
+func (idl *Idl) Enum(name string) (Enum, bool) {
+	for _, enum := range idl.Enums() {
+		if enum.Name == name {
+			return enum, true
+		}
+	}
+	return Enum{}, false
+}
+
+// Namespaces returns the sorted, distinct namespaces of the structs and
+// enums in the IDL.  e.g. "inc" for a struct named "inc.Response"
+func (idl *Idl) Namespaces() []string {
+	namespaces := []string{}
+	for _, el := range idl.elems {
+		if el.Type == "struct" || el.Type == "enum" {
+			ns, _ := splitNs(el.Name)
+			namespaces = addIfNotInSlice(ns, "", namespaces)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// TypeKind returns the kind of the named type
+func (idl *Idl) TypeKind(typeName string) TypeKind {
+	if stringInSlice(typeName, primitiveTypes) {
+		return KindPrimitive
+	}
+	if _, ok := idl.structs[typeName]; ok {
+		return KindStruct
+	}
+	if _, ok := idl.enums[typeName]; ok {
+		return KindEnum
+	}
+	return KindUnknown
+}
+
+// FieldKind returns the kind of the type of f.  Whether f is an
+// array is not considered.
+func (idl *Idl) FieldKind(f Field) TypeKind {
+	return idl.TypeKind(f.Type)
+}
+
+func copyFields(fields []Field) []Field {
+	if fields == nil {
+		return nil
+	}
+	c := make([]Field, len(fields))
+	copy(c, fields)
+	return c
+}
+
+func copyEnumValues(vals []EnumValue) []EnumValue {
+	if vals == nil {
+		return nil
+	}
+	c := make([]EnumValue, len(vals))
+	copy(c, vals)
+	return c
+}
+
+func copyFunctions(funcs []Function) []Function {
+	if funcs == nil {
+		return nil
+	}
+	c := make([]Function, len(funcs))
+	for i, fn := range funcs {
+		fn.Params = copyFields(fn.Params)
+		c[i] = fn
+	}
+	return c
+}
@@ -0,0 +1,57 @@
+package barrister
+
+import (
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestIdlIntrospection(t *testing.T) {
+	idl, err := ParseIdlJsonFile("conform/conform.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Equals(t, len(idl.Comments()), 1)
+	DeepEquals(t, idl.Namespaces(), []string{"inc"})
+
+	ifaces := idl.Interfaces()
+	Equals(t, len(ifaces), 2)
+	Equals(t, ifaces[0].Name, "A")
+	Equals(t, ifaces[1].Name, "B")
+	Equals(t, ifaces[1].Comment, "a second interface to prove that the server dispatcher\nunderstands how to distinguish between interfaces in a contract")
+	Equals(t, len(idl.Functions("A")), 7)
+	Equals(t, idl.Functions("A")[1].Name, "calc")
+	Equals(t, len(idl.Functions("C")), 0)
+
+	structs := idl.Structs()
+	Equals(t, len(structs), 5)
+	Equals(t, structs[0].Name, "inc.Response")
+
+	s, ok := idl.Struct("RepeatResponse")
+	Equals(t, ok, true)
+	Equals(t, s.Comment, "testing struct inheritance")
+	Equals(t, len(s.Fields), 2)
+	all := s.AllFields()
+	Equals(t, len(all), 3)
+	Equals(t, all[0].Name, "status")
+
+	// returned values are copies
+	all[0].Name = "changed"
+	s.Fields[0].Name = "changed"
+	s, _ = idl.Struct("RepeatResponse")
+	Equals(t, s.AllFields()[0].Name, "status")
+	Equals(t, s.Fields[0].Name, "count")
+
+	enum, ok := idl.Enum("inc.MathOp")
+	Equals(t, ok, true)
+	Equals(t, len(enum.Values), 2)
+	Equals(t, enum.Values[1].Value, "multiply")
+	_, ok = idl.Enum("MathOp")
+	Equals(t, ok, false)
+
+	Equals(t, idl.FieldKind(all[0]), KindEnum)
+	Equals(t, idl.FieldKind(idl.Method("A.repeat").Returns), KindStruct)
+	Equals(t, idl.TypeKind("float"), KindPrimitive)
+	Equals(t, idl.TypeKind("A"), KindUnknown)
+}
@@ -0,0 +1,54 @@
+package barrister
+
+import (
+	"context"
+	"fmt"
+)
+
+// Invoke calls method with client and returns its result as a T, for
+// programs that call a service without an idl2go generated proxy.  method is
+// fully qualified, e.g. "UserService.get", and T must be the Go type of its
+// return value, e.g.:
+//
+//	user, err := barrister.Invoke[*User](ctx, client, idl, "UserService.get", "u1")
+//
+// params are checked against the IDL before the request is sent.  Errors are
+// reported as a *JsonRpcError with code -32601 if idl has no such method and
+// -32602 if the params are invalid.  Otherwise the result is decoded as it
+// is by generated proxies, see CallInto.
+func Invoke[T any](ctx context.Context, client Client, idl *Idl, method string, params ...interface{}) (T, error) {
+	var res T
+
+	err := checkParams(idl, method, params)
+	if err != nil {
+		return res, err
+	}
+
+	err = CallInto(ctx, client, idl, method, &res, params...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return res, nil
+}
+
+// checkParams returns a *JsonRpcError if params are not valid for method
+func checkParams(idl *Idl, method string, params []interface{}) error {
+	fn, ok := idl.methods[method]
+	if !ok {
+		return &JsonRpcError{Code: -32601, Message: fmt.Sprintf("Unsupported method: %s", method)}
+	}
+
+	if len(params) != len(fn.Params) {
+		msg := fmt.Sprintf("Method %s expects %d params but was passed %d", method, len(fn.Params), len(params))
+		return &JsonRpcError{Code: -32602, Message: msg}
+	}
+
+	for i, p := range params {
+		err := checkGoValue(idl, &fn.Params[i], p, fmt.Sprintf("param[%d]", i))
+		if err != nil {
+			return &JsonRpcError{Code: -32602, Message: err.Error()}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package barrister
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func newInvokeClient() (Client, *Idl) {
+	svr := NewJSONServer(parseTestIdl(), true)
+	svr.AddHandler("A", AImpl{})
+	svr.AddHandler("B", BImpl{})
+	return NewRemoteClient(serverTransport{&svr}, false), svr.idl
+}
+
+func TestInvoke(t *testing.T) {
+	client, idl := newInvokeClient()
+	ctx := context.Background()
+
+	sum, err := Invoke[int64](ctx, client, idl, "A.add", 3, 4)
+	Equals(t, err, nil)
+	Equals(t, sum, int64(7))
+
+	nums, err := Invoke[[]int64](ctx, client, idl, "A.repeat_num", 2, 3)
+	Equals(t, err, nil)
+	DeepEquals(t, nums, []int64{})
+
+	hi, err := Invoke[HiResponse](ctx, client, idl, "A.say_hi")
+	Equals(t, err, nil)
+	Equals(t, hi.Hi, "hi")
+
+	// params may also be generic values
+	id, err := Invoke[string](ctx, client, idl, "A.putPerson",
+		map[string]interface{}{"personId": "p1", "firstName": "a", "lastName": "b", "email": nil})
+	Equals(t, err, nil)
+	Equals(t, id, "p1")
+
+	echo, err := Invoke[*string](ctx, client, idl, "B.echo", "return-null")
+	Equals(t, err, nil)
+	Equals(t, echo, (*string)(nil))
+}
+
+func TestInvokeChecksParams(t *testing.T) {
+	client, idl := newInvokeClient()
+	ctx := context.Background()
+
+	for _, c := range []struct {
+		method string
+		params []interface{}
+		code   int
+	}{
+		{"A.nope", []interface{}{}, -32601},
+		{"A.add", []interface{}{1}, -32602},
+		{"A.add", []interface{}{1, 1.5}, -32602},
+		{"A.add", []interface{}{1, "2"}, -32602},
+		{"A.calc", []interface{}{[]float64{1}, "divide"}, -32602},
+		{"A.calc", []interface{}{[]interface{}{1, "x"}, MathOpAdd}, -32602},
+		{"A.putPerson", []interface{}{map[string]interface{}{"personId": "p1"}}, -32602},
+		{"A.putPerson", []interface{}{nil}, -32602},
+	} {
+		_, err := Invoke[interface{}](ctx, client, idl, c.method, c.params...)
+		if e, ok := err.(*JsonRpcError); !ok || e.Code != c.code {
+			t.Errorf("%s %v: expected error code %d, got: %v", c.method, c.params, c.code, err)
+		}
+	}
+
+	// results are decoded as they are by proxies
+	_, err := Invoke[bool](ctx, client, idl, "A.add", 1, 2)
+	Equals(t, err.(*JsonRpcError).Code, -32000)
+}
@@ -0,0 +1,293 @@
+package barrister
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// The json* types mirror the JSON written by the Python translator, which
+// only includes the keys relevant to each element type.
+
+type jsonCommentElem struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type jsonEnumElem struct {
+	Type    string      `json:"type"`
+	Name    string      `json:"name"`
+	Comment string      `json:"comment"`
+	Values  []EnumValue `json:"values"`
+}
+
+type jsonStructElem struct {
+	Type    string      `json:"type"`
+	Name    string      `json:"name"`
+	Comment string      `json:"comment"`
+	Extends string      `json:"extends"`
+	Fields  []jsonField `json:"fields"`
+}
+
+type jsonField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional"`
+	IsArray  bool   `json:"is_array"`
+	Comment  string `json:"comment"`
+}
+
+type jsonInterfaceElem struct {
+	Type      string         `json:"type"`
+	Name      string         `json:"name"`
+	Comment   string         `json:"comment"`
+	Functions []jsonFunction `json:"functions"`
+}
+
+type jsonFunction struct {
+	Name    string      `json:"name"`
+	Comment string      `json:"comment"`
+	Params  []jsonParam `json:"params"`
+	Returns jsonReturns `json:"returns"`
+}
+
+type jsonParam struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	IsArray bool   `json:"is_array"`
+}
+
+type jsonReturns struct {
+	Type     string `json:"type"`
+	Optional bool   `json:"optional"`
+	IsArray  bool   `json:"is_array"`
+}
+
+type jsonMetaElem struct {
+	Type             string `json:"type"`
+	BarristerVersion string `json:"barrister_version"`
+	DateGenerated    int64  `json:"date_generated"`
+	Checksum         string `json:"checksum"`
+}
+
+// MarshalJSON encodes the IDL as the JSON document produced by the Barrister
+// translator.  Each element only contains the keys relevant to its type.
+func (idl *Idl) MarshalJSON() ([]byte, error) {
+	out := make([]interface{}, 0, len(idl.elems))
+	for _, el := range idl.elems {
+		switch el.Type {
+		case "comment":
+			out = append(out, jsonCommentElem{el.Type, el.Value})
+		case "enum":
+			vals := el.Values
+			if vals == nil {
+				vals = []EnumValue{}
+			}
+			out = append(out, jsonEnumElem{el.Type, el.Name, el.Comment, vals})
+		case "struct":
+			fields := make([]jsonField, len(el.Fields))
+			for i, f := range el.Fields {
+				fields[i] = jsonField(f)
+			}
+			out = append(out, jsonStructElem{el.Type, el.Name, el.Comment, el.Extends, fields})
+		case "interface":
+			funcs := make([]jsonFunction, len(el.Functions))
+			for i, fn := range el.Functions {
+				params := make([]jsonParam, len(fn.Params))
+				for x, p := range fn.Params {
+					params[x] = jsonParam{p.Name, p.Type, p.IsArray}
+				}
+				ret := jsonReturns{fn.Returns.Type, fn.Returns.Optional, fn.Returns.IsArray}
+				funcs[i] = jsonFunction{fn.Name, fn.Comment, params, ret}
+			}
+			out = append(out, jsonInterfaceElem{el.Type, el.Name, el.Comment, funcs})
+		case "meta":
+			out = append(out, jsonMetaElem{el.Type, el.BarristerVersion, el.DateGenerated, el.Checksum})
+		default:
+			out = append(out, el)
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes and validates IDL JSON, as ParseIdlJson does
+func (idl *Idl) UnmarshalJSON(b []byte) error {
+	parsed, err := ParseIdlJson(b)
+	if err != nil {
+		return err
+	}
+	*idl = *parsed
+	return nil
+}
+
+// FormatIdl renders the IDL as canonical Barrister IDL source.
+//
+// A map is returned whose keys are file names and values are IDL source.
+// Elements without a namespace are written to filename.  Structs and enums
+// in a namespace are written to a file per namespace named "<namespace>.idl",
+// which is imported where its first element occurred, so parsing the result
+// with ParseIdlFile yields the same elements in the same order.
+func (idl *Idl) FormatIdl(filename string) map[string][]byte {
+	files := map[string]*idlPrinter{}
+	getFile := func(ns string) *idlPrinter {
+		p, ok := files[ns]
+		if !ok {
+			p = &idlPrinter{b: &bytes.Buffer{}, namespace: ns}
+			if ns != "" {
+				line(p.b, 0, "namespace "+ns)
+				p.first = false
+			} else {
+				p.first = true
+			}
+			files[ns] = p
+		}
+		return p
+	}
+
+	main := getFile("")
+	for _, el := range idl.elems {
+		if el.Type == "meta" {
+			continue
+		}
+
+		ns, _ := splitNs(el.Name)
+		p := getFile(ns)
+		if ns != "" && !stringInSlice(ns, main.imports) {
+			main.imports = append(main.imports, ns)
+			main.separate()
+			line(main.b, 0, fmt.Sprintf("import \"%s.idl\"", ns))
+		}
+
+		for _, imp := range findAllImports(ns, []IdlJsonElem{el}) {
+			if ns != "" && !stringInSlice(imp, p.imports) {
+				p.imports = append(p.imports, imp)
+				p.separate()
+				line(p.b, 0, fmt.Sprintf("import \"%s.idl\"", imp))
+			}
+		}
+
+		p.separate()
+		p.elem(el)
+	}
+
+	out := map[string][]byte{}
+	for ns, p := range files {
+		name := filename
+		if ns != "" {
+			name = ns + ".idl"
+		}
+		out[name] = p.b.Bytes()
+	}
+	return out
+}
+
+type idlPrinter struct {
+	b         *bytes.Buffer
+	namespace string
+	imports   []string
+
+	// true until something has been written to b
+	first bool
+}
+
+// separate writes a blank line between top level declarations
+func (p *idlPrinter) separate() {
+	if !p.first {
+		line(p.b, 0, "")
+	}
+	p.first = false
+}
+
+// typeName strips the file's own namespace from type names
+func (p *idlPrinter) typeName(t string) string {
+	ns, name := splitNs(t)
+	if ns != "" && ns == p.namespace {
+		return name
+	}
+	return t
+}
+
+func (p *idlPrinter) fieldType(f Field) string {
+	s := p.typeName(f.Type)
+	if f.IsArray {
+		s = "[]" + s
+	}
+	return s
+}
+
+func (p *idlPrinter) comment(level int, comment string) {
+	if comment == "" {
+		return
+	}
+	for _, ln := range strings.Split(comment, "\n") {
+		if ln == "" {
+			line(p.b, level, "//")
+		} else {
+			line(p.b, level, "// "+ln)
+		}
+	}
+}
+
+func (p *idlPrinter) elem(el IdlJsonElem) {
+	switch el.Type {
+	case "comment":
+		p.comment(0, el.Value)
+	case "enum":
+		p.comment(0, el.Comment)
+		line(p.b, 0, fmt.Sprintf("enum %s {", p.typeName(el.Name)))
+		for _, v := range el.Values {
+			p.comment(1, v.Comment)
+			line(p.b, 1, v.Value)
+		}
+		line(p.b, 0, "}")
+	case "struct":
+		p.comment(0, el.Comment)
+		decl := "struct " + p.typeName(el.Name)
+		if el.Extends != "" {
+			decl += " extends " + p.typeName(el.Extends)
+		}
+		line(p.b, 0, decl+" {")
+
+		nameWidth, typeWidth := 0, 0
+		for _, f := range el.Fields {
+			nameWidth = maxInt(nameWidth, len(f.Name))
+			typeWidth = maxInt(typeWidth, len(p.fieldType(f)))
+		}
+		for _, f := range el.Fields {
+			p.comment(1, f.Comment)
+			if f.Optional {
+				line(p.b, 1, fmt.Sprintf("%-*s %-*s [optional]", nameWidth, f.Name, typeWidth, p.fieldType(f)))
+			} else {
+				line(p.b, 1, fmt.Sprintf("%-*s %s", nameWidth, f.Name, p.fieldType(f)))
+			}
+		}
+		line(p.b, 0, "}")
+	case "interface":
+		p.comment(0, el.Comment)
+		line(p.b, 0, fmt.Sprintf("interface %s {", el.Name))
+		for i, fn := range el.Functions {
+			if i > 0 {
+				line(p.b, 0, "")
+			}
+			p.comment(1, fn.Comment)
+			params := make([]string, len(fn.Params))
+			for x, param := range fn.Params {
+				params[x] = param.Name + " " + p.fieldType(param)
+			}
+			decl := fmt.Sprintf("%s(%s) %s", fn.Name, strings.Join(params, ", "), p.fieldType(fn.Returns))
+			if fn.Returns.Optional {
+				decl += " [optional]"
+			}
+			line(p.b, 1, decl)
+		}
+		line(p.b, 0, "}")
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,111 @@
+package barrister
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestMarshalJSONMatchesTranslator(t *testing.T) {
+	for _, fname := range []string{"test/conform.json", "conform/conform.json"} {
+		raw := readFile(fname)
+		idl, err := ParseIdlJson(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := json.Marshal(idl)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var expected, actual []map[string]interface{}
+		if err := json.Unmarshal(raw, &expected); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(b, &actual); err != nil {
+			t.Fatal(err)
+		}
+		DeepEquals(t, actual, expected)
+
+		var roundTrip Idl
+		if err := json.Unmarshal(b, &roundTrip); err != nil {
+			t.Fatal(err)
+		}
+		DeepEquals(t, roundTrip.elems, idl.elems)
+		DeepEquals(t, roundTrip.Meta, idl.Meta)
+	}
+}
+
+func TestFormatIdl(t *testing.T) {
+	idl := parseTestIdl()
+	files := idl.FormatIdl("conform.idl")
+	Equals(t, len(files), 1)
+
+	parsed, err := ParseIdl("conform.idl", files["conform.idl"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equals(t, len(parsed.elems), len(idl.elems))
+	for i, el := range idl.elems {
+		if el.Type != "meta" {
+			DeepEquals(t, parsed.elems[i], el)
+		}
+	}
+
+	// formatting is canonical
+	Equals(t, string(parsed.FormatIdl("conform.idl")["conform.idl"]), string(files["conform.idl"]))
+}
+
+func TestFormatIdlNamespacesRoundTrip(t *testing.T) {
+	idl, err := ParseIdlJsonFile("conform/conform.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := idl.FormatIdl("conform.idl")
+	Equals(t, len(files), 2)
+	Equals(t, string(files["inc.idl"]), `namespace inc
+
+enum Status {
+	ok
+	err
+}
+
+enum MathOp {
+	add
+	multiply
+}
+
+struct Response {
+	status Status
+}
+`)
+
+	dir, err := ioutil.TempDir("", "barrister")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, src := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), src, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parsed, err := ParseIdlFile(filepath.Join(dir, "conform.idl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equals(t, len(parsed.elems), len(idl.elems))
+	for i, el := range idl.elems {
+		if el.Type != "meta" {
+			DeepEquals(t, parsed.elems[i], el)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package barrister
+
+import (
+	"strings"
+)
+
+// OpenRpcVersion is the version of the OpenRPC specification written by ExportOpenRpc
+const OpenRpcVersion = "1.2.6"
+
+// JsonSchemaDraft is the "$schema" of documents written by ExportJsonSchema
+const JsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// JsonSchema is the subset of JSON Schema needed to describe IDL types
+type JsonSchema struct {
+	Ref         string                 `json:"$ref,omitempty"`
+	Type        string                 `json:"type,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Items       *JsonSchema            `json:"items,omitempty"`
+	Enum        []string               `json:"enum,omitempty"`
+	Properties  map[string]*JsonSchema `json:"properties,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	AllOf       []*JsonSchema          `json:"allOf,omitempty"`
+	OneOf       []*JsonSchema          `json:"oneOf,omitempty"`
+}
+
+// JsonSchemaDoc is a JSON Schema document holding a definition for every
+// struct and enum in an IDL
+type JsonSchemaDoc struct {
+	Schema      string                 `json:"$schema"`
+	Definitions map[string]*JsonSchema `json:"definitions"`
+}
+
+// OpenRpcDoc is an OpenRPC document.  See https://spec.open-rpc.org
+type OpenRpcDoc struct {
+	OpenRpc    string            `json:"openrpc"`
+	Info       OpenRpcInfo       `json:"info"`
+	Methods    []OpenRpcMethod   `json:"methods"`
+	Components OpenRpcComponents `json:"components"`
+}
+
+// OpenRpcInfo holds metadata about the API
+type OpenRpcInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenRpcMethod describes a single JSON-RPC method
+type OpenRpcMethod struct {
+	Name           string                     `json:"name"`
+	Description    string                     `json:"description,omitempty"`
+	ParamStructure string                     `json:"paramStructure"`
+	Params         []OpenRpcContentDescriptor `json:"params"`
+	Result         OpenRpcContentDescriptor   `json:"result"`
+}
+
+// OpenRpcContentDescriptor describes a method param or result
+type OpenRpcContentDescriptor struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Schema      *JsonSchema `json:"schema"`
+}
+
+// OpenRpcComponents holds the schemas referenced by methods
+type OpenRpcComponents struct {
+	Schemas map[string]*JsonSchema `json:"schemas"`
+}
+
+// ExportOpenRpc converts the IDL to an OpenRPC document.
+//
+// Each function becomes a method named "Interface.function" whose params are
+// passed by position.  Structs and enums are written to components/schemas,
+// see ExportJsonSchema for how they are converted.  Optional return values
+// are described as "oneOf" the return type or null.
+//
+// The IDL has no version of its own, so version is used as info.version.
+func (idl *Idl) ExportOpenRpc(title string, version string, useAllOf bool) *OpenRpcDoc {
+	doc := &OpenRpcDoc{
+		OpenRpc: OpenRpcVersion,
+		Info: OpenRpcInfo{
+			Title:       title,
+			Description: strings.Join(idl.Comments(), "\n\n"),
+			Version:     version,
+		},
+		Methods:    []OpenRpcMethod{},
+		Components: OpenRpcComponents{idl.schemaDefinitions("#/components/schemas/", useAllOf)},
+	}
+
+	for _, iface := range idl.Interfaces() {
+		for _, fn := range iface.Functions {
+			m := OpenRpcMethod{
+				Name:           iface.Name + "." + fn.Name,
+				Description:    fn.Comment,
+				ParamStructure: "by-position",
+				Params:         []OpenRpcContentDescriptor{},
+			}
+			for _, p := range fn.Params {
+				m.Params = append(m.Params, OpenRpcContentDescriptor{
+					Name:     p.Name,
+					Required: true,
+					Schema:   fieldSchema(p, "#/components/schemas/"),
+				})
+			}
+
+			result := fieldSchema(fn.Returns, "#/components/schemas/")
+			if fn.Returns.Optional {
+				result = &JsonSchema{OneOf: []*JsonSchema{result, {Type: "null"}}}
+			}
+			m.Result = OpenRpcContentDescriptor{Name: "result", Required: !fn.Returns.Optional, Schema: result}
+
+			doc.Methods = append(doc.Methods, m)
+		}
+	}
+
+	return doc
+}
+
+// ExportJsonSchema converts every struct and enum in the IDL to a JSON Schema
+// definition.  References between types use "#/definitions/<name>".
+//
+// Enums become strings restricted to the enum values.  Structs become objects
+// whose fields are required unless marked optional, and array fields use "items".
+// If useAllOf is true, a struct that extends another is written as "allOf" a
+// reference to its parent and its own fields, otherwise the inherited fields
+// are copied into the struct.
+func (idl *Idl) ExportJsonSchema(useAllOf bool) *JsonSchemaDoc {
+	return &JsonSchemaDoc{JsonSchemaDraft, idl.schemaDefinitions("#/definitions/", useAllOf)}
+}
+
+func (idl *Idl) schemaDefinitions(refPrefix string, useAllOf bool) map[string]*JsonSchema {
+	defs := map[string]*JsonSchema{}
+
+	for _, e := range idl.Enums() {
+		vals := make([]string, len(e.Values))
+		for i, v := range e.Values {
+			vals[i] = v.Value
+		}
+		defs[e.Name] = &JsonSchema{Type: "string", Description: e.Comment, Enum: vals}
+	}
+
+	for _, s := range idl.Structs() {
+		fields := s.AllFields()
+		if useAllOf {
+			fields = s.Fields
+		}
+
+		obj := &JsonSchema{Type: "object", Properties: map[string]*JsonSchema{}}
+		for _, f := range fields {
+			schema := fieldSchema(f, refPrefix)
+			if f.Comment != "" {
+				if schema.Ref != "" {
+					// siblings of $ref are ignored, so wrap it
+					schema = &JsonSchema{AllOf: []*JsonSchema{schema}}
+				}
+				schema.Description = f.Comment
+			}
+			obj.Properties[f.Name] = schema
+			if !f.Optional {
+				obj.Required = append(obj.Required, f.Name)
+			}
+		}
+
+		if useAllOf && s.Extends != "" {
+			defs[s.Name] = &JsonSchema{
+				Description: s.Comment,
+				AllOf:       []*JsonSchema{{Ref: refPrefix + s.Extends}, obj},
+			}
+		} else {
+			obj.Description = s.Comment
+			defs[s.Name] = obj
+		}
+	}
+
+	return defs
+}
+
+// fieldSchema returns the schema for the type of f, ignoring whether
+// it is optional
+func fieldSchema(f Field, refPrefix string) *JsonSchema {
+	var schema *JsonSchema
+	switch f.Type {
+	case "string":
+		schema = &JsonSchema{Type: "string"}
+	case "int":
+		schema = &JsonSchema{Type: "integer"}
+	case "float":
+		schema = &JsonSchema{Type: "number"}
+	case "bool":
+		schema = &JsonSchema{Type: "boolean"}
+	default:
+		schema = &JsonSchema{Ref: refPrefix + f.Type}
+	}
+
+	if f.IsArray {
+		return &JsonSchema{Type: "array", Items: schema}
+	}
+	return schema
+}
@@ -0,0 +1,69 @@
+package barrister
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+var schemaTestIdl = `
+enum Color { red green }
+
+struct Base {
+	id string
+}
+
+struct Item extends Base {
+	// the item color
+	color  Color
+	tags   []string [optional]
+}
+
+interface Store {
+	// fetch an item
+	get(id string, colors []Color) Item [optional]
+}
+`
+
+func toJson(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestExportJsonSchemaFlattened(t *testing.T) {
+	doc := MustParseIdl("store.idl", []byte(schemaTestIdl)).ExportJsonSchema(false)
+	Equals(t, doc.Schema, JsonSchemaDraft)
+	Equals(t, len(doc.Definitions), 3)
+	Equals(t, toJson(t, doc.Definitions["Color"]), `{"type":"string","enum":["red","green"]}`)
+	Equals(t, toJson(t, doc.Definitions["Item"]), `{"type":"object","properties":{`+
+		`"color":{"description":"the item color","allOf":[{"$ref":"#/definitions/Color"}]},`+
+		`"id":{"type":"string"},`+
+		`"tags":{"type":"array","items":{"type":"string"}}},`+
+		`"required":["id","color"]}`)
+}
+
+func TestExportJsonSchemaAllOf(t *testing.T) {
+	doc := MustParseIdl("store.idl", []byte(schemaTestIdl)).ExportJsonSchema(true)
+	Equals(t, toJson(t, doc.Definitions["Item"]), `{"allOf":[{"$ref":"#/definitions/Base"},`+
+		`{"type":"object","properties":{`+
+		`"color":{"description":"the item color","allOf":[{"$ref":"#/definitions/Color"}]},`+
+		`"tags":{"type":"array","items":{"type":"string"}}},`+
+		`"required":["color"]}]}`)
+	Equals(t, toJson(t, doc.Definitions["Base"]), `{"type":"object","properties":{"id":{"type":"string"}},"required":["id"]}`)
+}
+
+func TestExportOpenRpc(t *testing.T) {
+	doc := MustParseIdl("store.idl", []byte(schemaTestIdl)).ExportOpenRpc("Store", "1.0", false)
+	Equals(t, doc.OpenRpc, OpenRpcVersion)
+	Equals(t, toJson(t, doc.Info), `{"title":"Store","version":"1.0"}`)
+	Equals(t, len(doc.Components.Schemas), 3)
+	Equals(t, len(doc.Methods), 1)
+	Equals(t, toJson(t, doc.Methods[0]), `{"name":"Store.get","description":"fetch an item","paramStructure":"by-position",`+
+		`"params":[{"name":"id","required":true,"schema":{"type":"string"}},`+
+		`{"name":"colors","required":true,"schema":{"type":"array","items":{"$ref":"#/components/schemas/Color"}}}],`+
+		`"result":{"name":"result","schema":{"oneOf":[{"$ref":"#/components/schemas/Item"},{"type":"null"}]}}}`)
+}
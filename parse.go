@@ -0,0 +1,609 @@
+package barrister
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ParserVersion is written to the meta element of IDLs parsed from
+// .idl source, in place of the Python translator version
+const ParserVersion = "0.1.6"
+
+// ParseError describes a syntax error in Barrister IDL source
+type ParseError struct {
+	Filename string
+	Line     int
+	Column   int
+	Msg      string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("barrister: %s:%d:%d: %s", e.Filename, e.Line, e.Column, e.Msg)
+}
+
+// ParseIdlFile loads and parses the Barrister IDL source (.idl) in filename.
+// Imported files are resolved relative to the directory of filename.
+func ParseIdlFile(filename string) (*Idl, error) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseIdl(filename, src)
+}
+
+// LoadIdlFile loads filename as IDL source if it has a .idl extension,
+// otherwise as IDL JSON produced by the translator.
+func LoadIdlFile(filename string) (*Idl, error) {
+	if strings.ToLower(filepath.Ext(filename)) == ".idl" {
+		return ParseIdlFile(filename)
+	}
+	return ParseIdlJsonFile(filename)
+}
+
+// ParseIdl parses Barrister IDL source and returns the same Idl that
+// ParseIdlJson would return for the translated JSON.  filename is used in
+// error messages and to resolve imported files.
+func ParseIdl(filename string, src []byte) (*Idl, error) {
+	elems, err := ParseIdlElems(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	return NewValidatedIdl(elems)
+}
+
+// MustParseIdl calls ParseIdl and panics if an error is returned
+func MustParseIdl(filename string, src []byte) *Idl {
+	idl, err := ParseIdl(filename, src)
+	if err != nil {
+		panic(err)
+	}
+	return idl
+}
+
+// ParseIdlElems parses Barrister IDL source into the elements the Python
+// translator would emit as JSON, including a trailing meta element.
+//
+// So that code generated from the same source is reproducible, the meta
+// date_generated is taken from the SOURCE_DATE_EPOCH environment variable
+// (seconds since epoch) and is zero if the variable is not set.
+func ParseIdlElems(filename string, src []byte) ([]IdlJsonElem, error) {
+	p := newIdlParser(filename, src, map[string]bool{})
+	elems, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	meta := IdlJsonElem{
+		Type:             "meta",
+		BarristerVersion: ParserVersion,
+		DateGenerated:    sourceDateEpoch() * 1000,
+		Checksum:         elemsChecksum(elems),
+	}
+	return append(elems, meta), nil
+}
+
+// sourceDateEpoch returns the SOURCE_DATE_EPOCH environment variable,
+// or 0 if it is not set or invalid
+func sourceDateEpoch() int64 {
+	epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return epoch
+}
+
+//////////////////////////////////////////////////
+// Lexer //
+///////////
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+	col  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	}
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+// commentLine is a single // comment with the comment marker and
+// one leading space removed
+type commentLine struct {
+	text string
+	line int
+}
+
+type lexer struct {
+	filename string
+	src      []byte
+	pos      int
+	line     int
+	col      int
+
+	// line of the last token returned, used to ignore trailing comments
+	tokLine int
+
+	// comments read since they were last taken by the parser
+	comments []commentLine
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || c == '.' || (c >= '0' && c <= '9')
+}
+
+func (l *lexer) errorf(line, col int, format string, args ...interface{}) error {
+	return &ParseError{l.filename, line, col, fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) advance() byte {
+	c := l.src[l.pos]
+	l.pos++
+	if c == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return c
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		line, col := l.line, l.col
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			l.advance()
+		case c == '/':
+			if l.pos+1 >= len(l.src) || l.src[l.pos+1] != '/' {
+				return token{}, l.errorf(line, col, "unexpected character '/'")
+			}
+			start := l.pos + 2
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.advance()
+			}
+			text := strings.TrimSuffix(string(l.src[start:l.pos]), "\r")
+			text = strings.TrimPrefix(text, " ")
+			if line != l.tokLine {
+				l.comments = append(l.comments, commentLine{text, line})
+			}
+		case isIdentStart(c):
+			start := l.pos
+			for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+				l.advance()
+			}
+			l.tokLine = line
+			return token{tokIdent, string(l.src[start:l.pos]), line, col}, nil
+		case c == '"':
+			l.advance()
+			start := l.pos
+			for l.pos < len(l.src) && l.src[l.pos] != '"' && l.src[l.pos] != '\n' {
+				l.advance()
+			}
+			if l.pos >= len(l.src) || l.src[l.pos] != '"' {
+				return token{}, l.errorf(line, col, "unterminated string")
+			}
+			text := string(l.src[start:l.pos])
+			l.advance()
+			l.tokLine = line
+			return token{tokString, text, line, col}, nil
+		case strings.IndexByte("{}()[],", c) > -1:
+			l.advance()
+			l.tokLine = line
+			return token{tokPunct, string(c), line, col}, nil
+		default:
+			return token{}, l.errorf(line, col, "unexpected character %q", c)
+		}
+	}
+	return token{tokEOF, "", l.line, l.col}, nil
+}
+
+//////////////////////////////////////////////////
+// Parser //
+////////////
+
+var primitiveTypes = []string{"string", "int", "float", "bool"}
+
+type idlParser struct {
+	lex *lexer
+	tok token
+
+	// namespace declared by this file, prepended to struct and enum names
+	namespace string
+
+	// true once the first struct, enum or interface has been parsed
+	seenElem bool
+
+	// absolute paths of files already parsed, shared with imported files
+	// to skip repeated imports and break import cycles
+	parsed map[string]bool
+
+	elems []IdlJsonElem
+}
+
+func newIdlParser(filename string, src []byte, parsed map[string]bool) *idlParser {
+	if abs, err := filepath.Abs(filename); err == nil {
+		parsed[abs] = true
+	}
+	return &idlParser{
+		lex:    &lexer{filename: filename, src: src, line: 1, col: 1},
+		parsed: parsed,
+	}
+}
+
+func (p *idlParser) errorf(t token, format string, args ...interface{}) error {
+	return p.lex.errorf(t.line, t.col, format, args...)
+}
+
+func (p *idlParser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *idlParser) isPunct(s string) bool {
+	return p.tok.kind == tokPunct && p.tok.text == s
+}
+
+func (p *idlParser) expectPunct(s string) error {
+	if !p.isPunct(s) {
+		return p.errorf(p.tok, "expected '%s' but found %s", s, p.tok)
+	}
+	return p.advance()
+}
+
+func (p *idlParser) expectIdent(what string) (token, error) {
+	t := p.tok
+	if t.kind != tokIdent {
+		return t, p.errorf(t, "expected %s but found %s", what, t)
+	}
+	return t, p.advance()
+}
+
+// expectName reads an identifier that names a declaration, which may not be
+// qualified with a namespace
+func (p *idlParser) expectName(what string) (string, error) {
+	t, err := p.expectIdent(what)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(t.text, ".") {
+		return "", p.errorf(t, "invalid name '%s': names may not contain '.'", t.text)
+	}
+	return t.text, nil
+}
+
+// takeComment returns the comment block that ends on the line directly above
+// t and clears all pending comments.  If topLevel is true, pending comment
+// blocks that are not attached to t are added as "comment" elements.
+func (p *idlParser) takeComment(t token, topLevel bool) string {
+	var blocks [][]string
+	last := -1
+	for _, c := range p.lex.comments {
+		if len(blocks) == 0 || c.line != last+1 {
+			blocks = append(blocks, []string{})
+		}
+		blocks[len(blocks)-1] = append(blocks[len(blocks)-1], c.text)
+		last = c.line
+	}
+	p.lex.comments = nil
+
+	attached := ""
+	if len(blocks) > 0 && last == t.line-1 && t.kind != tokEOF {
+		attached = joinComment(blocks[len(blocks)-1])
+		blocks = blocks[:len(blocks)-1]
+	}
+
+	if topLevel {
+		for _, b := range blocks {
+			p.elems = append(p.elems, IdlJsonElem{Type: "comment", Value: joinComment(b)})
+		}
+	}
+	return attached
+}
+
+// joinComment joins comment lines, dropping leading and trailing empty lines
+func joinComment(lines []string) string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// qualify prefixes a type declared in this file with the file's namespace
+func (p *idlParser) qualify(typeName string) string {
+	if p.namespace == "" || typeName == "" || strings.Contains(typeName, ".") ||
+		stringInSlice(typeName, primitiveTypes) {
+		return typeName
+	}
+	return p.namespace + "." + typeName
+}
+
+func (p *idlParser) parse() ([]IdlJsonElem, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind != tokEOF {
+		t := p.tok
+		if t.kind != tokIdent {
+			return nil, p.errorf(t, "expected struct, enum, interface, namespace or import but found %s", t)
+		}
+
+		comment := p.takeComment(t, true)
+		var err error
+		switch t.text {
+		case "namespace", "import":
+			if comment != "" {
+				p.elems = append(p.elems, IdlJsonElem{Type: "comment", Value: comment})
+			}
+			if t.text == "namespace" {
+				err = p.parseNamespace()
+			} else {
+				err = p.parseImport()
+			}
+		case "struct":
+			err = p.parseStruct(comment)
+		case "enum":
+			err = p.parseEnum(comment)
+		case "interface":
+			err = p.parseInterface(comment)
+		default:
+			err = p.errorf(t, "expected struct, enum, interface, namespace or import but found %s", t)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.takeComment(p.tok, true)
+
+	return p.elems, nil
+}
+
+func (p *idlParser) parseNamespace() error {
+	t := p.tok
+	if p.namespace != "" {
+		return p.errorf(t, "namespace already declared as '%s'", p.namespace)
+	}
+	if p.seenElem {
+		return p.errorf(t, "namespace must be declared before any struct, enum or interface")
+	}
+	if err := p.advance(); err != nil {
+		return err
+	}
+	ns, err := p.expectName("namespace")
+	if err != nil {
+		return err
+	}
+	p.namespace = ns
+	return nil
+}
+
+func (p *idlParser) parseImport() error {
+	if err := p.advance(); err != nil {
+		return err
+	}
+	t := p.tok
+	if t.kind != tokString {
+		return p.errorf(t, "expected import filename string but found %s", t)
+	}
+
+	path := t.text
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(p.lex.filename), path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return p.errorf(t, "unable to resolve import '%s': %s", t.text, err)
+	}
+
+	if !p.parsed[abs] {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return p.errorf(t, "unable to read import '%s': %s", t.text, err)
+		}
+		imported, err := newIdlParser(path, src, p.parsed).parse()
+		if err != nil {
+			return err
+		}
+		for _, el := range imported {
+			if el.Type != "comment" {
+				p.elems = append(p.elems, el)
+			}
+		}
+	}
+
+	return p.advance()
+}
+
+// parseType reads an optionally array type and an optional "[optional]" marker
+func (p *idlParser) parseType(f *Field, allowOptional bool) error {
+	if p.isPunct("[") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return err
+		}
+		f.IsArray = true
+	}
+
+	t, err := p.expectIdent("type")
+	if err != nil {
+		return err
+	}
+	f.Type = p.qualify(t.text)
+
+	if allowOptional && p.isPunct("[") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		t := p.tok
+		if t.kind != tokIdent || t.text != "optional" {
+			return p.errorf(t, "expected 'optional' but found %s", t)
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return err
+		}
+		f.Optional = true
+	}
+	return nil
+}
+
+func (p *idlParser) parseStruct(comment string) error {
+	p.seenElem = true
+	if err := p.advance(); err != nil {
+		return err
+	}
+	name, err := p.expectName("struct name")
+	if err != nil {
+		return err
+	}
+	el := IdlJsonElem{Type: "struct", Name: p.qualify(name), Comment: comment, Fields: []Field{}}
+
+	if p.tok.kind == tokIdent && p.tok.text == "extends" {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		t, err := p.expectIdent("struct name")
+		if err != nil {
+			return err
+		}
+		el.Extends = p.qualify(t.text)
+	}
+
+	if err := p.expectPunct("{"); err != nil {
+		return err
+	}
+	for !p.isPunct("}") {
+		t := p.tok
+		f := Field{Comment: p.takeComment(t, false)}
+		if f.Name, err = p.expectName("field name or '}'"); err != nil {
+			return err
+		}
+		if err := p.parseType(&f, true); err != nil {
+			return err
+		}
+		el.Fields = append(el.Fields, f)
+	}
+	p.takeComment(p.tok, false)
+
+	p.elems = append(p.elems, el)
+	return p.advance()
+}
+
+func (p *idlParser) parseEnum(comment string) error {
+	p.seenElem = true
+	if err := p.advance(); err != nil {
+		return err
+	}
+	name, err := p.expectName("enum name")
+	if err != nil {
+		return err
+	}
+	el := IdlJsonElem{Type: "enum", Name: p.qualify(name), Comment: comment, Values: []EnumValue{}}
+
+	if err := p.expectPunct("{"); err != nil {
+		return err
+	}
+	for !p.isPunct("}") {
+		t := p.tok
+		v := EnumValue{Comment: p.takeComment(t, false)}
+		if v.Value, err = p.expectName("enum value or '}'"); err != nil {
+			return err
+		}
+		el.Values = append(el.Values, v)
+	}
+	p.takeComment(p.tok, false)
+
+	p.elems = append(p.elems, el)
+	return p.advance()
+}
+
+func (p *idlParser) parseInterface(comment string) error {
+	p.seenElem = true
+	if err := p.advance(); err != nil {
+		return err
+	}
+	name, err := p.expectName("interface name")
+	if err != nil {
+		return err
+	}
+	el := IdlJsonElem{Type: "interface", Name: name, Comment: comment, Functions: []Function{}}
+
+	if err := p.expectPunct("{"); err != nil {
+		return err
+	}
+	for !p.isPunct("}") {
+		t := p.tok
+		fn := Function{Comment: p.takeComment(t, false), Params: []Field{}}
+		if fn.Name, err = p.expectName("function name or '}'"); err != nil {
+			return err
+		}
+
+		if err := p.expectPunct("("); err != nil {
+			return err
+		}
+		for !p.isPunct(")") {
+			if len(fn.Params) > 0 {
+				if err := p.expectPunct(","); err != nil {
+					return err
+				}
+			}
+			param := Field{}
+			if param.Name, err = p.expectName("param name"); err != nil {
+				return err
+			}
+			if err := p.parseType(&param, false); err != nil {
+				return err
+			}
+			fn.Params = append(fn.Params, param)
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+
+		if err := p.parseType(&fn.Returns, true); err != nil {
+			return err
+		}
+		el.Functions = append(el.Functions, fn)
+	}
+	p.takeComment(p.tok, false)
+
+	p.elems = append(p.elems, el)
+	return p.advance()
+}
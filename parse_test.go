@@ -0,0 +1,110 @@
+package barrister
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestParseIdlMatchesTranslatorJson(t *testing.T) {
+	idl, err := ParseIdlFile("test/conform.idl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := parseTestIdl()
+
+	Equals(t, len(idl.elems), len(expected.elems))
+	for i, ex := range expected.elems {
+		if ex.Type == "meta" {
+			Equals(t, idl.elems[i].Type, "meta")
+			continue
+		}
+		DeepEquals(t, idl.elems[i], ex)
+	}
+	DeepEquals(t, idl.interfaces, expected.interfaces)
+	DeepEquals(t, idl.structs, expected.structs)
+	DeepEquals(t, idl.enums, expected.enums)
+}
+
+func TestParseIdlNamespaceImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "barrister")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inc := `namespace inc
+
+enum Status { ok err }
+
+struct Response {
+    status Status
+}
+`
+	main := `import "inc.idl"
+
+struct RepeatResponse extends inc.Response {
+    items []string [optional]
+}
+
+interface A {
+    repeat(s string, status inc.Status) RepeatResponse
+}
+`
+	err = ioutil.WriteFile(filepath.Join(dir, "inc.idl"), []byte(inc), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idl, err := ParseIdl(filepath.Join(dir, "main.idl"), []byte(main))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, el := range idl.elems {
+		names = append(names, el.Type+" "+el.Name)
+	}
+	DeepEquals(t, names, []string{"enum inc.Status", "struct inc.Response",
+		"struct RepeatResponse", "interface A", "meta "})
+
+	Equals(t, idl.structs["inc.Response"].Fields[0].Type, "inc.Status")
+	Equals(t, idl.structs["RepeatResponse"].Extends, "inc.Response")
+	Equals(t, len(idl.structs["RepeatResponse"].allFields), 2)
+	DeepEquals(t, idl.structs["RepeatResponse"].Fields[0],
+		Field{Name: "items", Type: "string", IsArray: true, Optional: true})
+	Equals(t, idl.Method("A.repeat").Params[1].Type, "inc.Status")
+	Equals(t, idl.Meta.BarristerVersion, ParserVersion)
+}
+
+func TestParseIdlErrors(t *testing.T) {
+	cases := []struct {
+		src string
+		err string
+	}{
+		{"struct {", "bad.idl:1:8: expected struct name but found '{'"},
+		{"struct A {\n  a\n}", "bad.idl:3:1: expected type but found '}'"},
+		{"struct A {\n  a string [opt]\n}", "bad.idl:2:13: expected 'optional' but found 'opt'"},
+		{"enum E {\n  a.b\n}", "bad.idl:2:3: invalid name 'a.b'"},
+		{"interface I {\n  f(a int b int) int\n}", "bad.idl:2:11: expected ',' but found 'b'"},
+		{"interface I {\n  f(a int)", "bad.idl:2:11: expected type but found end of file"},
+		{"enum E { a }\nnamespace foo", "bad.idl:2:1: namespace must be declared before"},
+		{"foo Bar", "bad.idl:1:1: expected struct, enum, interface, namespace or import but found 'foo'"},
+		{"struct A { a string } /", "bad.idl:1:23: unexpected character '/'"},
+		{"import \"missing.idl\"", "bad.idl:1:8: unable to read import 'missing.idl'"},
+	}
+
+	for _, c := range cases {
+		_, err := ParseIdl("bad.idl", []byte(c.src))
+		if err == nil {
+			t.Errorf("Expected error parsing: %s", c.src)
+		} else if !strings.Contains(err.Error(), c.err) {
+			t.Errorf("Parsing: %s\nexpected error containing: %s\ngot: %s", c.src, c.err, err)
+		} else if _, ok := err.(*ParseError); !ok {
+			t.Errorf("Expected *ParseError, got %T", err)
+		}
+	}
+}
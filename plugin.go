@@ -0,0 +1,74 @@
+package barrister
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// PluginRequest is written as JSON to the STDIN of a code generator plugin
+// run by idl2go -plugin.  Like a protoc plugin, the plugin generates code
+// from the parsed IDL, and writes a PluginResponse to STDOUT.
+type PluginRequest struct {
+	// The parsed IDL, encoded as IDL JSON
+	Idl *Idl `json:"idl"`
+
+	// Settings passed to idl2go.  Templates is always nil.
+	Options GenerateOptions `json:"options"`
+}
+
+// PluginResponse is written as JSON to STDOUT by a code generator plugin
+type PluginResponse struct {
+	// Generated files, written by idl2go relative to its output directory
+	Files []PluginFile `json:"files"`
+
+	// If not empty, generation failed and no files are written
+	Error string `json:"error,omitempty"`
+}
+
+// PluginFile is a file generated by a plugin
+type PluginFile struct {
+	// Path relative to the output directory, separated by "/"
+	Name string `json:"name"`
+
+	Content string `json:"content"`
+}
+
+// RunPlugin implements the plugin side of idl2go -plugin.  It reads a
+// PluginRequest from in, and writes the files returned by generate, which
+// are keyed by path as in Idl.GenerateGoFiles, to out as a PluginResponse.
+// An error returned by generate is reported in the response.  A plugin's
+// main function is typically:
+//
+//	err := barrister.RunPlugin(os.Stdin, os.Stdout, generate)
+//	if err != nil {
+//		fmt.Fprintln(os.Stderr, err)
+//		os.Exit(1)
+//	}
+func RunPlugin(in io.Reader, out io.Writer, generate func(req *PluginRequest) (map[string][]byte, error)) error {
+	req := &PluginRequest{}
+	err := json.NewDecoder(in).Decode(req)
+	if err != nil {
+		return fmt.Errorf("barrister: invalid plugin request: %s", err)
+	}
+	if req.Idl == nil {
+		return fmt.Errorf("barrister: invalid plugin request: no IDL")
+	}
+
+	resp := PluginResponse{Files: []PluginFile{}}
+	files, err := generate(req)
+	if err != nil {
+		resp.Error = err.Error()
+	} else {
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			resp.Files = append(resp.Files, PluginFile{name, string(files[name])})
+		}
+	}
+	return json.NewEncoder(out).Encode(resp)
+}
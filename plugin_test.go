@@ -0,0 +1,49 @@
+package barrister
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestRunPlugin(t *testing.T) {
+	idl := MustParseIdl("svc.idl", []byte(`
+interface Users {
+	get(id string) string
+}
+`))
+	in, err := json.Marshal(&PluginRequest{idl, GenerateOptions{DefaultPkgName: "svc", Mocks: true}})
+	Equals(t, err, nil)
+
+	out := &bytes.Buffer{}
+	err = RunPlugin(bytes.NewReader(in), out, func(req *PluginRequest) (map[string][]byte, error) {
+		Equals(t, req.Options.DefaultPkgName, "svc")
+		Equals(t, req.Options.Mocks, true)
+		Equals(t, req.Idl.ComputeChecksum(), idl.ComputeChecksum())
+		files := map[string][]byte{}
+		for _, iface := range req.Idl.Interfaces() {
+			files["svc/"+iface.Name+".txt"] = []byte(iface.Functions[0].Name)
+		}
+		files["README"] = []byte("generated")
+		return files, nil
+	})
+	Equals(t, err, nil)
+
+	resp := PluginResponse{}
+	Equals(t, json.Unmarshal(out.Bytes(), &resp), nil)
+	DeepEquals(t, resp, PluginResponse{Files: []PluginFile{{"README", "generated"}, {"svc/Users.txt", "get"}}})
+
+	// errors are reported in the response
+	out.Reset()
+	err = RunPlugin(bytes.NewReader(in), out, func(req *PluginRequest) (map[string][]byte, error) {
+		return nil, fmt.Errorf("unsupported")
+	})
+	Equals(t, err, nil)
+	Equals(t, out.String(), `{"files":[],"error":"unsupported"}`+"\n")
+
+	err = RunPlugin(bytes.NewReader([]byte(`{}`)), out, nil)
+	Equals(t, err.Error(), "barrister: invalid plugin request: no IDL")
+}
@@ -0,0 +1,445 @@
+package barrister
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// defaultTemplates generate the Go code for a package.  The "file" template
+// generates the package's .go file and calls the others.
+//
+//go:embed templates/go/*.tmpl
+var defaultTemplates embed.FS
+
+const withContextIfaceNameSuffix = "WithContext"
+
+// TemplateData is the data the "file" template, and any file templates added
+// with GenerateOptions.Templates, are executed with for each Go package
+// generated from an IDL.
+//
+// Besides the text/template builtins, templates may call these functions:
+//
+//	typeName name              Go name of an IDL struct or enum, or the Go type it is mapped to
+//	fieldName name             Go name of an IDL struct field or param, e.g. PersonID
+//	fieldType struct field     Go type of a field of struct
+//	paramType iface fn param   Go type of a param of fn
+//	returnType fn              Go type of the return value of fn
+//	returnZero fn              zero value of the return type of fn
+//	params iface fn            Go params of fn, e.g. "ctx context.Context, id string"
+//	args iface fn ctx          names of the params of fn, with ctx first if iface.Context
+//	fieldValidations struct    checks made by the Validate method of struct, see FieldValidation
+//	enumConsts enum            Go names of the enum's values, e.g. StatusOk
+//	quotedValues enum          the enum's values in single quotes, e.g. 'ok'
+//	isMapped name              true if the IDL type is mapped to a Go type with GenerateOptions.Types
+//	comment text               text as Go line comments, ending in a newline
+//	rawString s                s as a Go raw string literal
+//	capitalize s, lower s      s with its first letter in upper case, or all of s in lower case
+//	ident s                    s, prefixed with "_" if it is a Go keyword
+//	baseName name              name without its namespace, e.g. "Status" for "inc.Status"
+//	join elems sep             strings.Join
+//
+// Code generated for files ending in ".go" is formatted with go/format, so
+// templates do not need to be indented correctly.
+type TemplateData struct {
+	// The full IDL, including elements that are generated in other packages
+	Idl *Idl
+
+	// Go package name
+	Package string
+
+	// File level IDL comments, used as the package doc
+	Comments []string
+
+	// Packages imported by the generated code, sorted by path
+	Imports []TemplateImport
+
+	// Enums and structs in this package in IDL order, excluding those mapped
+	// to existing Go types with GenerateOptions.Types
+	Enums   []Enum
+	Structs []*Struct
+
+	// Go interfaces to generate, sorted by IDL interface name.  With
+	// IncludeContextBoth, there are two for each IDL interface.
+	Interfaces []TemplateInterface
+
+	// NewServer functions to generate: one, or two with IncludeContextBoth
+	Servers []TemplateServer
+
+	// The full IDL as indented JSON, which generated code embeds
+	IdlJson string
+
+	// Settings the code is generated with
+	Options GenerateOptions
+}
+
+// TemplateInterface is a Go interface generated for an IDL interface
+type TemplateInterface struct {
+	Interface
+
+	// Go name of the interface, e.g. "UserService" or "UserServiceWithContext"
+	GoName string
+
+	// True if methods take a context.Context as their first param
+	Context bool
+}
+
+// TemplateServer describes the NewJSONServer and NewServer functions that
+// register a handler for each interface with a barrister.Server
+type TemplateServer struct {
+	// Suffix of the function names, e.g. "WithContext"
+	Suffix string
+
+	Interfaces []TemplateInterface
+}
+
+// TemplateImport is a package imported by generated code
+type TemplateImport struct {
+	// Name the package is imported as, or "" to use its package name
+	Name string
+
+	Path string
+}
+
+// FieldValidation describes how the generated Validate method of a struct
+// checks one of its fields
+type FieldValidation struct {
+	// Go expression for the field, e.g. "_s.Tags"
+	GoField string
+
+	// Path reported in a ValidationError, e.g. "User.tags"
+	Path string
+
+	// True if the field is a required array, which must not be nil
+	Required bool
+
+	// True if the field is an enum or struct whose Validate method is called
+	Validate bool
+
+	// True if the field is an array, whose elements are validated
+	IsArray bool
+
+	// True if the field is a pointer, only validated when not nil
+	NilCheck bool
+
+	// True if the field is an optional enum, only validated when not empty
+	EmptyCheck bool
+}
+
+// template returns the default templates, with those in g.opts.Templates
+// parsed over them
+func (g *generateGo) template() (*template.Template, error) {
+	if g.templates != nil {
+		return g.templates, nil
+	}
+
+	t, err := template.New("").Funcs(g.funcs()).ParseFS(defaultTemplates, "templates/go/*.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("barrister: default templates: %s", err)
+	}
+	if g.opts.Templates != nil {
+		t, err = t.ParseFS(g.opts.Templates, "*.tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("barrister: templates: %s", err)
+		}
+	}
+	g.templates = t
+	return t, nil
+}
+
+// execute writes the output of the named template to b
+func (g *generateGo) execute(b *bytes.Buffer, name string, data interface{}) error {
+	t, err := g.template()
+	if err != nil {
+		return err
+	}
+	err = t.ExecuteTemplate(b, name, data)
+	if err != nil {
+		return fmt.Errorf("barrister: template: %s", err)
+	}
+	return nil
+}
+
+// generate returns the code generated for the package by the "file" template
+// under the name "<package>.go", and the files generated by the templates in
+// g.opts.Templates whose names have two extensions, e.g. "extra.go.tmpl"
+// generates "extra.go".  Files that are empty or only contain whitespace are
+// not returned.
+func (g *generateGo) generate() (map[string][]byte, error) {
+	data, err := g.templateData()
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{g.pkgName + ".go": "file"}
+	if g.opts.Templates != nil {
+		files, err := fs.Glob(g.opts.Templates, "*.tmpl")
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			name := strings.TrimSuffix(f, ".tmpl")
+			if strings.Contains(name, ".") {
+				names[name] = f
+			}
+		}
+	}
+
+	files := map[string][]byte{}
+	for name, tmpl := range names {
+		b := &bytes.Buffer{}
+		err = g.execute(b, tmpl, data)
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(b.Bytes())) == 0 {
+			continue
+		}
+
+		code := b.Bytes()
+		if strings.HasSuffix(name, ".go") {
+			code, err = formatGo(g.pkgName, code)
+			if err != nil {
+				return nil, err
+			}
+		}
+		files[name] = code
+	}
+	return files, nil
+}
+
+// templateData returns the TemplateData for the package g generates
+func (g *generateGo) templateData() (*TemplateData, error) {
+	g.typeImports = map[string]bool{}
+
+	data := &TemplateData{
+		Idl:     g.idl,
+		Package: g.pkgName,
+		Options: g.opts,
+	}
+
+	for _, elem := range g.pkgIdl.elems {
+		switch {
+		case elem.Type == "comment" && elem.Value != "":
+			data.Comments = append(data.Comments, elem.Value)
+		case elem.Type == "enum" && !g.isMapped(elem.Name):
+			data.Enums = append(data.Enums, Enum{elem.Name, elem.Comment, copyEnumValues(elem.Values)})
+		case elem.Type == "struct" && !g.isMapped(elem.Name):
+			s, ok := g.idl.Struct(elem.Name)
+			if !ok {
+				panic("No struct found: " + elem.Name)
+			}
+			data.Structs = append(data.Structs, s)
+		}
+	}
+
+	if g.hasInterface() {
+		server := TemplateServer{}
+		serverWithContext := TemplateServer{Suffix: withContextIfaceNameSuffix}
+		for _, name := range sortedKeys(g.pkgIdl.interfaces) {
+			iface, _ := g.idl.Interface(name)
+			goName := capitalize(name)
+			ti := TemplateInterface{iface, goName, g.includeContext == IncludeContextYes}
+			data.Interfaces = append(data.Interfaces, ti)
+			server.Interfaces = append(server.Interfaces, ti)
+
+			if g.includeContext == IncludeContextBoth {
+				ti = TemplateInterface{iface, goName + withContextIfaceNameSuffix, true}
+				data.Interfaces = append(data.Interfaces, ti)
+				serverWithContext.Interfaces = append(serverWithContext.Interfaces, ti)
+			}
+		}
+		data.Servers = append(data.Servers, server)
+		if g.includeContext == IncludeContextBoth {
+			data.Servers = append(data.Servers, serverWithContext)
+		}
+
+		idlbytes, err := json.MarshalIndent(g.idl, "", "    ")
+		if err != nil {
+			return nil, err
+		}
+		data.IdlJson = string(idlbytes)
+	}
+
+	data.Imports = g.importPackages(data)
+	return data, nil
+}
+
+// importPackages returns the packages imported by the code generated for data
+func (g *generateGo) importPackages(data *TemplateData) []TemplateImport {
+	// find the packages of the mapped Go types that are used
+	for _, s := range data.Structs {
+		if s.Extends != "" {
+			g.mappedType(s.Extends, "")
+		}
+		for _, f := range s.Fields {
+			g.goType(f, s.Name+"."+f.Name)
+		}
+	}
+	for _, iface := range data.Interfaces {
+		for _, fn := range iface.Functions {
+			g.goType(fn.Returns, "")
+			for _, p := range fn.Params {
+				g.goType(p, paramKey(iface.Name, fn, p))
+			}
+		}
+	}
+
+	imports := []TemplateImport{}
+	add := func(paths ...string) {
+		for _, p := range paths {
+			imports = append(imports, TemplateImport{Path: p})
+		}
+	}
+	if len(data.Interfaces) > 0 {
+		add("context", "fmt")
+		if g.mocks {
+			add("sync")
+		}
+	}
+	if len(data.Enums) > 0 {
+		add("encoding/json")
+	}
+	if len(data.Interfaces) > 0 || len(data.Enums) > 0 || len(data.Structs) > 0 {
+		add(g.opts.runtimeImport())
+	}
+	for _, ns := range g.imports {
+		// generated code refers to types in the package by namespace
+		p, name := g.opts.nsPackage(ns)
+		if name == ns {
+			name = ""
+		} else {
+			name = ns
+		}
+		imports = append(imports, TemplateImport{name, p})
+	}
+	for p := range g.typeImports {
+		add(p)
+	}
+	sort.Slice(imports, func(i, j int) bool { return imports[i].Path < imports[j].Path })
+	return imports
+}
+
+func (g *generateGo) funcs() template.FuncMap {
+	return template.FuncMap{
+		"typeName": func(name string) string {
+			if t, ok := g.mappedType(name, ""); ok {
+				return t
+			}
+			return capitalizeAndStripMatchingPkg(name, g.pkgName)
+		},
+		"fieldName": fieldGoName,
+		"fieldType": func(s *Struct, f Field) string {
+			return g.goType(f, s.Name+"."+f.Name)
+		},
+		"paramType": func(iface TemplateInterface, fn Function, p Field) string {
+			return g.goType(p, paramKey(iface.Name, fn, p))
+		},
+		"returnType": func(fn Function) string {
+			return g.goType(fn.Returns, "")
+		},
+		"returnZero": func(fn Function) string {
+			return g.zeroVal(fn.Returns, "")
+		},
+		"params": func(iface TemplateInterface, fn Function) string {
+			return strings.Join(g.funcParams(iface.Name, fn, iface.Context), ", ")
+		},
+		"args": func(iface TemplateInterface, fn Function, ctx string) string {
+			args := make([]string, 0, len(fn.Params)+1)
+			if iface.Context {
+				args = append(args, ctx)
+			}
+			for _, p := range fn.Params {
+				args = append(args, escReserved(p.Name))
+			}
+			return strings.Join(args, ", ")
+		},
+		"fieldValidations": g.fieldValidations,
+		"enumConsts": func(e Enum) []string {
+			goName := capitalizeAndStripMatchingPkg(e.Name, g.pkgName)
+			consts := make([]string, len(e.Values))
+			for i, val := range e.Values {
+				consts[i] = goName + capitalize(val.Value)
+			}
+			return consts
+		},
+		"quotedValues": func(e Enum) []string {
+			quoted := make([]string, len(e.Values))
+			for i, val := range e.Values {
+				quoted[i] = "'" + val.Value + "'"
+			}
+			return quoted
+		},
+		"isMapped":   g.isMapped,
+		"comment":    commentLines,
+		"rawString":  rawString,
+		"capitalize": capitalize,
+		"lower":      strings.ToLower,
+		"ident":      escReserved,
+		"baseName": func(name string) string {
+			_, base := splitNs(name)
+			return base
+		},
+		"join": strings.Join,
+	}
+}
+
+// fieldValidations returns the checks the Validate method of s makes on its
+// fields
+func (g *generateGo) fieldValidations(s *Struct) []FieldValidation {
+	_, name := splitNs(s.Name)
+
+	checks := make([]FieldValidation, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		key := s.Name + "." + f.Name
+		_, isEnum := g.idl.enums[f.Type]
+		_, isStruct := g.idl.structs[f.Type]
+		if _, mapped := g.mappedType(f.Type, key); mapped {
+			// the Go type may not have a Validate method
+			isEnum, isStruct = false, false
+		}
+
+		// nil pointers and empty optional enums are omitted from the JSON
+		isPtr := strings.HasPrefix(g.goType(f, key), "*")
+		check := FieldValidation{
+			GoField:    "_s." + fieldGoName(f.Name),
+			Path:       name + "." + f.Name,
+			Required:   f.IsArray && !f.Optional,
+			Validate:   isEnum || isStruct,
+			IsArray:    f.IsArray,
+			NilCheck:   isPtr,
+			EmptyCheck: !isPtr && f.Optional && isEnum && !f.IsArray,
+		}
+		if check.Required || check.Validate {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// commentLines returns comment as Go line comments, each ending in a newline
+func commentLines(comment string) string {
+	if comment == "" {
+		return ""
+	}
+	b := &bytes.Buffer{}
+	for _, ln := range strings.Split(comment, "\n") {
+		ln = strings.TrimRight(ln, " \t")
+		if ln == "" {
+			line(b, 0, "//")
+		} else {
+			line(b, 0, "// "+ln)
+		}
+	}
+	return b.String()
+}
+
+// rawString returns s as a Go raw string literal
+func rawString(s string) string {
+	return "`" + strings.Replace(s, "`", "`+\"`\"+`", -1) + "`"
+}
@@ -0,0 +1,176 @@
+// JSON-RPC 2.0 envelope, as sent and received by barrister.RemoteClient
+
+export interface JsonRpcRequest {
+  jsonrpc: "2.0";
+  id: string;
+  method: string;
+  params: unknown[];
+}
+
+export interface JsonRpcError {
+  code: number;
+  message: string;
+  data?: unknown;
+}
+
+export interface JsonRpcResponse {
+  jsonrpc: "2.0";
+  id: string;
+  result?: unknown;
+  error?: JsonRpcError;
+}
+
+/** Error thrown for JSON-RPC errors returned by the server, or transport errors */
+export class RpcError extends Error {
+  code: number;
+  data?: unknown;
+
+  constructor(code: number, message: string, data?: unknown) {
+    super(message);
+    this.name = "RpcError";
+    this.code = code;
+    this.data = data;
+  }
+}
+
+/** Sends a serialized JSON-RPC request and resolves to the serialized response */
+export interface Transport {
+  send(body: string): Promise<string>;
+}
+
+/** Transport that POSTs requests to url with fetch */
+export class HttpTransport implements Transport {
+  url: string;
+  headers: Record<string, string>;
+
+  constructor(url: string, headers: Record<string, string> = {}) {
+    this.url = url;
+    this.headers = headers;
+  }
+
+  async send(body: string): Promise<string> {
+    const headers = Object.assign({ "Content-Type": "application/json" }, this.headers);
+    const resp = await fetch(this.url, { method: "POST", headers: headers, body: body });
+    if (!resp.ok) {
+      throw new Error("HttpTransport POST to " + this.url + " returned non-2xx status: " + resp.status);
+    }
+    return resp.text();
+  }
+}
+
+/** Invokes JSON-RPC methods.  Implemented by RemoteClient and Batch. */
+export interface Caller {
+  call<T>(method: string, params: unknown[]): Promise<T>;
+}
+
+let nextRequestId = 0;
+
+function newRequest(method: string, params: unknown[]): JsonRpcRequest {
+  nextRequestId++;
+  return { jsonrpc: "2.0", id: String(nextRequestId), method: method, params: params };
+}
+
+function resultOf<T>(resp: JsonRpcResponse): T {
+  if (resp.error) {
+    throw new RpcError(resp.error.code, resp.error.message, resp.error.data);
+  }
+  return (resp.result === undefined ? null : resp.result) as T;
+}
+
+/** Calls methods on a server over a Transport */
+export class RemoteClient implements Caller {
+  transport: Transport;
+
+  constructor(transport: Transport) {
+    this.transport = transport;
+  }
+
+  async call<T>(method: string, params: unknown[]): Promise<T> {
+    const resp = await this.send(method, newRequest(method, params));
+    return resultOf<T>(resp as JsonRpcResponse);
+  }
+
+  /** Sends requests as a single JSON-RPC batch, and resolves to their responses in any order */
+  async callBatch(requests: JsonRpcRequest[]): Promise<JsonRpcResponse[]> {
+    const resp = await this.send("CallBatch", requests);
+    if (!Array.isArray(resp)) {
+      throw new RpcError(-32603, "barrister: CallBatch response is not an array");
+    }
+    return resp;
+  }
+
+  /** Returns a Batch, whose calls are sent together by Batch.send */
+  batch(): Batch {
+    return new Batch(this);
+  }
+
+  private async send(method: string, req: JsonRpcRequest | JsonRpcRequest[]): Promise<JsonRpcResponse | JsonRpcResponse[]> {
+    let body: string;
+    try {
+      body = await this.transport.send(JSON.stringify(req));
+    } catch (e) {
+      throw new RpcError(-32603, "barrister: " + method + ": Transport error during request: " + e);
+    }
+    try {
+      return JSON.parse(body);
+    } catch (e) {
+      throw new RpcError(-32603, "barrister: " + method + ": Call unable to Unmarshal response: " + e);
+    }
+  }
+}
+
+/**
+ * Collects calls, e.g. made with a generated client, and sends them as one
+ * JSON-RPC batch request.  The promise returned by each call settles once
+ * send is called and the batch response is received.
+ */
+export class Batch implements Caller {
+  private client: RemoteClient;
+  private requests: JsonRpcRequest[] = [];
+  private pending: Map<string, { resolve: (v: unknown) => void; reject: (e: unknown) => void }> = new Map();
+
+  constructor(client: RemoteClient) {
+    this.client = client;
+  }
+
+  call<T>(method: string, params: unknown[]): Promise<T> {
+    const req = newRequest(method, params);
+    this.requests.push(req);
+    return new Promise<T>((resolve, reject) => {
+      this.pending.set(req.id, { resolve: resolve as (v: unknown) => void, reject: reject });
+    });
+  }
+
+  /** Sends the calls made so far, and settles their promises */
+  async send(): Promise<void> {
+    const requests = this.requests;
+    const pending = this.pending;
+    this.requests = [];
+    this.pending = new Map();
+    if (requests.length === 0) {
+      return;
+    }
+
+    let responses: JsonRpcResponse[];
+    try {
+      responses = await this.client.callBatch(requests);
+    } catch (e) {
+      pending.forEach((p) => p.reject(e));
+      return;
+    }
+
+    for (const resp of responses) {
+      const p = pending.get(resp.id);
+      if (!p) {
+        continue;
+      }
+      pending.delete(resp.id);
+      try {
+        p.resolve(resultOf(resp));
+      } catch (e) {
+        p.reject(e);
+      }
+    }
+    pending.forEach((p, id) => p.reject(new RpcError(-32603, "barrister: no response for request " + id)));
+  }
+}
@@ -0,0 +1,65 @@
+package barrister
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TransportFactory returns a Transport that sends requests to the service at u
+type TransportFactory func(u *url.URL) (Transport, error)
+
+var transports = struct {
+	sync.RWMutex
+	m map[string]TransportFactory
+}{m: map[string]TransportFactory{
+	"http":  newHttpTransport,
+	"https": newHttpTransport,
+}}
+
+func newHttpTransport(u *url.URL) (Transport, error) {
+	return &HttpTransport{Url: u.String()}, nil
+}
+
+// RegisterTransport makes factory available to NewTransport for URLs with the
+// given scheme, so programs such as the barrister command can call services
+// over any transport.  Packages that implement a transport typically call
+// RegisterTransport from an init function.  "http" and "https" are registered
+// by default, and create an *HttpTransport.
+func RegisterTransport(scheme string, factory TransportFactory) {
+	transports.Lock()
+	defer transports.Unlock()
+	transports.m[strings.ToLower(scheme)] = factory
+}
+
+// NewTransport returns a Transport for the service at rawurl, created by the
+// TransportFactory registered for the URL's scheme
+func NewTransport(rawurl string) (Transport, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("barrister: invalid transport URL %s: %s", rawurl, err)
+	}
+
+	transports.RLock()
+	factory, ok := transports.m[strings.ToLower(u.Scheme)]
+	transports.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("barrister: no transport registered for URL %s. Registered schemes: %s",
+			rawurl, strings.Join(TransportSchemes(), ", "))
+	}
+	return factory(u)
+}
+
+// TransportSchemes returns the URL schemes transports are registered for, sorted
+func TransportSchemes() []string {
+	transports.RLock()
+	defer transports.RUnlock()
+	schemes := make([]string, 0, len(transports.m))
+	for scheme := range transports.m {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
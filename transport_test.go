@@ -0,0 +1,32 @@
+package barrister
+
+import (
+	"net/url"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestNewTransport(t *testing.T) {
+	trans, err := NewTransport("http://localhost:8080/calc")
+	Equals(t, err, nil)
+	Equals(t, trans.(*HttpTransport).Url, "http://localhost:8080/calc")
+
+	_, err = NewTransport("mem://calc")
+	Equals(t, err.Error(), "barrister: no transport registered for URL mem://calc. Registered schemes: http, https")
+
+	server := NewServer(parseTestIdl(), &JsonSerializer{})
+	RegisterTransport("mem", func(u *url.URL) (Transport, error) {
+		return &InMemoryTransport{Server: &server}, nil
+	})
+	defer func() {
+		transports.Lock()
+		delete(transports.m, "mem")
+		transports.Unlock()
+	}()
+
+	trans, err = NewTransport("MEM://calc")
+	Equals(t, err, nil)
+	Equals(t, trans.(*InMemoryTransport).Server, &server)
+	DeepEquals(t, TransportSchemes(), []string{"http", "https", "mem"})
+}
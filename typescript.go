@@ -0,0 +1,243 @@
+package barrister
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// tsRuntime is the JSON-RPC client included in each generated TypeScript file
+//
+//go:embed templates/ts/runtime.ts
+var tsRuntime string
+
+var tsReservedWords = []string{
+	"break", "case", "catch", "class", "const", "continue", "debugger",
+	"default", "delete", "do", "else", "enum", "export", "extends", "false",
+	"finally", "for", "function", "if", "implements", "import", "in",
+	"instanceof", "interface", "let", "new", "null", "package", "private",
+	"protected", "public", "return", "static", "super", "switch", "this",
+	"throw", "true", "try", "typeof", "var", "void", "while", "with", "yield",
+}
+
+// GenerateTypeScript generates a TypeScript module for the IDL, for web
+// frontends that call Barrister services.  Typically you'll use the idl2ts
+// binary as a front end to this method.
+//
+// Structs are generated as interfaces, which extend the interface of the
+// struct they extend.  Optional fields are declared with "?" and may also be
+// null.  Enums are generated as unions of their string values.  Structs and
+// enums in a namespace are declared in a TypeScript namespace of the same
+// name, so IDL type names can be used unchanged, e.g. "inc.Status".
+//
+// For each IDL interface, a TypeScript interface and a client class of the
+// same name with the suffix "Client" are generated.  Clients call methods
+// with a Caller: a RemoteClient, which sends each call as a JSON-RPC request
+// with a Transport such as HttpTransport, or a Batch, which sends the calls
+// made with it as a single JSON-RPC batch request.
+func (idl *Idl) GenerateTypeScript() []byte {
+	b := &bytes.Buffer{}
+	tsLine(b, 0, "// Code generated by idl2ts. DO NOT EDIT.")
+	tsLine(b, 0, fmt.Sprintf("// Source: Barrister IDL v%s", idl.Meta.BarristerVersion))
+	for _, c := range idl.Comments() {
+		if c != "" {
+			tsLine(b, 0, "//")
+			b.WriteString(commentLines(c))
+		}
+	}
+	tsLine(b, 0, "")
+	tsLine(b, 0, "/* eslint-disable */")
+	tsLine(b, 0, "")
+	tsLine(b, 0, fmt.Sprintf("export const BarristerVersion = %q;", idl.Meta.BarristerVersion))
+	tsLine(b, 0, fmt.Sprintf("export const BarristerChecksum = %q;", idl.Meta.Checksum))
+	tsLine(b, 0, "")
+
+	// structs and enums, grouped by namespace
+	namespaced := map[string][]IdlJsonElem{}
+	for _, el := range idl.elems {
+		if el.Type == "struct" || el.Type == "enum" {
+			ns, _ := splitNs(el.Name)
+			namespaced[ns] = append(namespaced[ns], el)
+		}
+	}
+	for _, el := range namespaced[""] {
+		tsElem(b, 0, el)
+		tsLine(b, 0, "")
+	}
+	namespaces := make([]string, 0, len(namespaced))
+	for ns := range namespaced {
+		if ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	for _, ns := range namespaces {
+		tsLine(b, 0, fmt.Sprintf("export namespace %s {", ns))
+		for i, el := range namespaced[ns] {
+			if i > 0 {
+				tsLine(b, 0, "")
+			}
+			tsElem(b, 1, el)
+		}
+		tsLine(b, 0, "}")
+		tsLine(b, 0, "")
+	}
+
+	for _, iface := range idl.Interfaces() {
+		tsInterface(b, iface)
+	}
+
+	b.WriteString(tsRuntime)
+	return b.Bytes()
+}
+
+// tsElem writes the TypeScript type for a struct or enum element
+func tsElem(b *bytes.Buffer, level int, el IdlJsonElem) {
+	_, name := splitNs(el.Name)
+	tsComment(b, level, el.Comment)
+
+	if el.Type == "enum" {
+		vals := make([]string, len(el.Values))
+		for i, v := range el.Values {
+			vals[i] = fmt.Sprintf("%q", v.Value)
+		}
+		if len(vals) == 0 {
+			vals = append(vals, "never")
+		}
+		tsLine(b, level, fmt.Sprintf("export type %s = %s;", name, strings.Join(vals, " | ")))
+		return
+	}
+
+	extends := ""
+	if el.Extends != "" {
+		extends = " extends " + el.Extends
+	}
+	tsLine(b, level, fmt.Sprintf("export interface %s%s {", name, extends))
+	for _, f := range el.Fields {
+		tsComment(b, level+1, f.Comment)
+		if f.Optional {
+			tsLine(b, level+1, fmt.Sprintf("%s?: %s | null;", f.Name, tsType(f)))
+		} else {
+			tsLine(b, level+1, fmt.Sprintf("%s: %s;", f.Name, tsType(f)))
+		}
+	}
+	tsLine(b, level, "}")
+}
+
+// tsInterface writes a TypeScript interface for iface, and a client class
+// that implements it
+func tsInterface(b *bytes.Buffer, iface Interface) {
+	tsComment(b, 0, iface.Comment)
+	tsLine(b, 0, fmt.Sprintf("export interface %s {", iface.Name))
+	for _, fn := range iface.Functions {
+		tsComment(b, 1, fn.Comment)
+		tsLine(b, 1, fmt.Sprintf("%s(%s): %s;", fn.Name, tsParams(fn), tsReturnType(fn)))
+	}
+	tsLine(b, 0, "}")
+	tsLine(b, 0, "")
+
+	tsLine(b, 0, fmt.Sprintf("/** Calls %s methods with a RemoteClient or Batch */", iface.Name))
+	tsLine(b, 0, fmt.Sprintf("export class %sClient implements %s {", iface.Name, iface.Name))
+	tsLine(b, 1, "caller: Caller;")
+	tsLine(b, 0, "")
+	tsLine(b, 1, "constructor(caller: Caller) {")
+	tsLine(b, 2, "this.caller = caller;")
+	tsLine(b, 1, "}")
+	for _, fn := range iface.Functions {
+		args := make([]string, len(fn.Params))
+		for i, p := range fn.Params {
+			args[i] = tsIdent(p.Name)
+		}
+		tsLine(b, 0, "")
+		tsComment(b, 1, fn.Comment)
+		tsLine(b, 1, fmt.Sprintf("%s(%s): %s {", fn.Name, tsParams(fn), tsReturnType(fn)))
+		tsLine(b, 2, fmt.Sprintf("return this.caller.call<%s>(%q, [%s]);",
+			tsResultType(fn), iface.Name+"."+fn.Name, strings.Join(args, ", ")))
+		tsLine(b, 1, "}")
+	}
+	tsLine(b, 0, "}")
+	tsLine(b, 0, "")
+}
+
+func tsParams(fn Function) string {
+	params := make([]string, len(fn.Params))
+	for i, p := range fn.Params {
+		params[i] = fmt.Sprintf("%s: %s", tsIdent(p.Name), tsType(p))
+	}
+	return strings.Join(params, ", ")
+}
+
+func tsReturnType(fn Function) string {
+	return "Promise<" + tsResultType(fn) + ">"
+}
+
+func tsResultType(fn Function) string {
+	t := tsType(fn.Returns)
+	if fn.Returns.Optional {
+		t += " | null"
+	}
+	return t
+}
+
+// tsType returns the TypeScript type of f, not including null for optional fields
+func tsType(f Field) string {
+	t := f.Type
+	switch f.Type {
+	case "string":
+		t = "string"
+	case "int", "float":
+		t = "number"
+	case "bool":
+		t = "boolean"
+	}
+	if f.IsArray {
+		t += "[]"
+	}
+	return t
+}
+
+// tsIdent returns s, prefixed with "_" if it is a reserved word
+func tsIdent(s string) string {
+	for _, word := range tsReservedWords {
+		if word == s {
+			return "_" + s
+		}
+	}
+	return s
+}
+
+// tsComment writes comment as a JSDoc comment
+func tsComment(b *bytes.Buffer, level int, comment string) {
+	comment = strings.TrimRight(comment, " \t\n")
+	if comment == "" {
+		return
+	}
+	comment = strings.Replace(comment, "*/", "*\\/", -1)
+
+	lines := strings.Split(comment, "\n")
+	if len(lines) == 1 {
+		tsLine(b, level, "/** "+strings.TrimSpace(lines[0])+" */")
+		return
+	}
+	tsLine(b, level, "/**")
+	for _, ln := range lines {
+		ln = strings.TrimRight(ln, " \t")
+		if ln == "" {
+			tsLine(b, level, " *")
+		} else {
+			tsLine(b, level, " * "+ln)
+		}
+	}
+	tsLine(b, level, " */")
+}
+
+// tsLine writes s indented by level, with two spaces per level
+func tsLine(b *bytes.Buffer, level int, s string) {
+	if s != "" {
+		b.WriteString(strings.Repeat("  ", level))
+		b.WriteString(s)
+	}
+	b.WriteString("\n")
+}
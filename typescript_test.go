@@ -0,0 +1,51 @@
+package barrister
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateTypeScript(t *testing.T) {
+	ts := string(MustParseIdl("svc.idl", []byte(`
+namespace inc
+
+enum Status {
+	ok
+	err
+}
+
+// Base type
+struct Response {
+	status Status
+}
+
+struct User extends Response {
+	name  string
+	email string [optional]
+	tags  []string
+}
+
+// Manages users
+interface UserService {
+	// looks up a user
+	get(id int, default bool) User [optional]
+	count() int
+}
+`)).GenerateTypeScript())
+
+	for _, expected := range []string{
+		"// Code generated by idl2ts. DO NOT EDIT.\n// Source: Barrister IDL v" + ParserVersion + "\n",
+		"export namespace inc {\n  export type Status = \"ok\" | \"err\";\n",
+		"  /** Base type */\n  export interface Response {\n    status: inc.Status;\n  }\n",
+		"  export interface User extends inc.Response {\n    name: string;\n    email?: string | null;\n    tags: string[];\n  }\n",
+		"/** Manages users */\nexport interface UserService {\n  /** looks up a user */\n  get(id: number, _default: boolean): Promise<inc.User | null>;\n",
+		"export class UserServiceClient implements UserService {",
+		"    return this.caller.call<inc.User | null>(\"UserService.get\", [id, _default]);\n",
+		"    return this.caller.call<number>(\"UserService.count\", []);\n",
+		"export class Batch implements Caller {",
+	} {
+		if !strings.Contains(ts, expected) {
+			t.Errorf("TypeScript does not contain %q:\n%s", expected, ts)
+		}
+	}
+}
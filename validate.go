@@ -0,0 +1,312 @@
+package barrister
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IdlError describes a single semantic problem found in an IDL, such
+// as a field whose type is not defined.
+type IdlError struct {
+	// Type of the element containing the problem: "struct", "enum" or "interface"
+	ElemType string
+
+	// Name of the element containing the problem
+	Elem string
+
+	// Name of the interface function, if the problem is in a function
+	Function string
+
+	// Name of the struct field or function param, if the problem is in one.
+	// Function return values are reported as "returns".
+	Field string
+
+	// Description of the problem
+	Msg string
+}
+
+// Location returns a description of where the problem occurred.
+// e.g. "interface A function add param b"
+func (e *IdlError) Location() string {
+	loc := e.ElemType + " " + e.Elem
+	if e.Function != "" {
+		loc += " function " + e.Function
+		if e.Field == "returns" {
+			loc += " returns"
+		} else if e.Field != "" {
+			loc += " param " + e.Field
+		}
+	} else if e.Field != "" {
+		if e.ElemType == "enum" {
+			loc += " value " + e.Field
+		} else {
+			loc += " field " + e.Field
+		}
+	}
+	return loc
+}
+
+func (e *IdlError) Error() string {
+	return fmt.Sprintf("barrister: %s: %s", e.Location(), e.Msg)
+}
+
+// IdlErrors is the list of problems found by Idl.Validate
+type IdlErrors []*IdlError
+
+func (e IdlErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// NewValidatedIdl creates a new Idl like NewIdl and validates it.  If any
+// problems are found the returned error is an IdlErrors listing all of them.
+func NewValidatedIdl(elems []IdlJsonElem) (*Idl, error) {
+	idl := NewIdl(elems)
+	err := idl.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return idl, nil
+}
+
+// Validate checks that the IDL is internally consistent: element names are
+// unique, all types referenced by fields, params and return values are
+// defined, structs only extend existing structs without cycles and do not
+// redeclare inherited fields, and enums have unique values.
+//
+// Returns nil if the IDL is valid, otherwise an IdlErrors
+func (idl *Idl) Validate() error {
+	v := &idlValidator{idl: idl, seen: map[string]string{}}
+	for _, el := range idl.elems {
+		switch el.Type {
+		case "struct":
+			v.validateName(el)
+			v.validateStruct(el)
+		case "enum":
+			v.validateName(el)
+			v.validateEnum(el)
+		case "interface":
+			v.validateName(el)
+			v.validateInterface(el)
+		case "comment", "meta":
+		default:
+			v.add(&IdlError{ElemType: el.Type, Elem: el.Name,
+				Msg: fmt.Sprintf("unknown element type '%s'", el.Type)})
+		}
+	}
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+type idlValidator struct {
+	idl  *Idl
+	errs IdlErrors
+
+	// element type of each element name seen so far
+	seen map[string]string
+}
+
+func (v *idlValidator) add(err *IdlError) {
+	v.errs = append(v.errs, err)
+}
+
+func (v *idlValidator) validateName(el IdlJsonElem) {
+	if el.Name == "" {
+		v.add(&IdlError{ElemType: el.Type, Msg: "missing name"})
+		return
+	}
+
+	prev, ok := v.seen[el.Name]
+	if ok {
+		v.add(&IdlError{ElemType: el.Type, Elem: el.Name,
+			Msg: fmt.Sprintf("duplicate name, already declared as %s", prev)})
+	} else {
+		v.seen[el.Name] = el.Type
+	}
+}
+
+// validateType checks that f refers to a primitive, struct or enum type
+func (v *idlValidator) validateType(f Field, loc IdlError) {
+	msg := ""
+	if f.Type == "" {
+		msg = "missing type"
+	} else if v.idl.TypeKind(f.Type) == KindUnknown {
+		msg = fmt.Sprintf("unknown type '%s'", f.Type)
+	}
+
+	if msg != "" {
+		loc.Msg = msg
+		v.add(&loc)
+	}
+}
+
+func (v *idlValidator) validateStruct(el IdlJsonElem) {
+	loc := IdlError{ElemType: "struct", Elem: el.Name}
+
+	// fields inherited from parents, keyed by name with the declaring struct as value
+	inherited := map[string]string{}
+	if el.Extends != "" {
+		path := []string{el.Name}
+		parent := el.Extends
+		for parent != "" {
+			if stringInSlice(parent, path) {
+				// structs that extend a cycle without being part of it are
+				// not reported, the structs in the cycle are
+				if parent == el.Name {
+					err := loc
+					err.Msg = fmt.Sprintf("cyclic inheritance: %s -> %s",
+						strings.Join(path, " -> "), parent)
+					v.add(&err)
+				}
+				break
+			}
+
+			s, ok := v.idl.structs[parent]
+			if !ok {
+				err := loc
+				if _, isEnum := v.idl.enums[parent]; isEnum {
+					err.Msg = fmt.Sprintf("extends enum '%s', only structs may be extended", parent)
+				} else {
+					err.Msg = fmt.Sprintf("extends unknown struct '%s'", parent)
+				}
+				// report missing parents only where they are declared
+				if len(path) == 1 {
+					v.add(&err)
+				}
+				break
+			}
+
+			for _, f := range s.Fields {
+				if _, ok := inherited[f.Name]; !ok {
+					inherited[f.Name] = s.Name
+				}
+			}
+			path = append(path, parent)
+			parent = s.Extends
+		}
+	}
+
+	names := map[string]bool{}
+	for _, f := range el.Fields {
+		err := loc
+		err.Field = f.Name
+		if f.Name == "" {
+			err.Msg = "missing field name"
+			v.add(&err)
+		} else if names[f.Name] {
+			err.Msg = "duplicate field name"
+			v.add(&err)
+		} else if parent, ok := inherited[f.Name]; ok {
+			err.Msg = fmt.Sprintf("shadows field inherited from %s", parent)
+			v.add(&err)
+		}
+		names[f.Name] = true
+
+		v.validateType(f, err)
+	}
+}
+
+func (v *idlValidator) validateEnum(el IdlJsonElem) {
+	loc := IdlError{ElemType: "enum", Elem: el.Name}
+	if len(el.Values) == 0 {
+		err := loc
+		err.Msg = "enum has no values"
+		v.add(&err)
+	}
+
+	values := map[string]bool{}
+	for _, val := range el.Values {
+		err := loc
+		err.Field = val.Value
+		if val.Value == "" {
+			err.Msg = "missing value"
+			v.add(&err)
+		} else if values[val.Value] {
+			err.Msg = "duplicate value"
+			v.add(&err)
+		}
+		values[val.Value] = true
+	}
+}
+
+func (v *idlValidator) validateInterface(el IdlJsonElem) {
+	loc := IdlError{ElemType: "interface", Elem: el.Name}
+
+	funcs := map[string]bool{}
+	for _, fn := range el.Functions {
+		fnLoc := loc
+		fnLoc.Function = fn.Name
+		if fn.Name == "" {
+			err := fnLoc
+			err.Msg = "missing function name"
+			v.add(&err)
+		} else if funcs[fn.Name] {
+			err := fnLoc
+			err.Msg = "duplicate function name"
+			v.add(&err)
+		}
+		funcs[fn.Name] = true
+
+		params := map[string]bool{}
+		for _, p := range fn.Params {
+			err := fnLoc
+			err.Field = p.Name
+			if p.Name == "" {
+				err.Msg = "missing param name"
+				v.add(&err)
+			} else if params[p.Name] {
+				err.Msg = "duplicate param name"
+				v.add(&err)
+			}
+			params[p.Name] = true
+
+			v.validateType(p, err)
+		}
+
+		err := fnLoc
+		err.Field = "returns"
+		v.validateType(fn.Returns, err)
+	}
+}
+
+// ValidationError is returned by the Validate methods idl2go generates for
+// structs and enums
+type ValidationError struct {
+	// Location of the invalid value, starting with the name of the type
+	// that was validated, e.g. "Person.addresses[0].country"
+	Path string
+
+	// Description of the problem
+	Msg string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("barrister: %s: %s", e.Path, e.Msg)
+}
+
+// ValidationErrorAt is called by generated Validate methods to report an error
+// returned by the Validate method of a field at path.  If err is a
+// *ValidationError, the type name at the start of its Path is replaced with path.
+func ValidationErrorAt(path string, err error) error {
+	ve, ok := err.(*ValidationError)
+	if !ok {
+		return err
+	}
+	rest := ""
+	if i := strings.IndexAny(ve.Path, ".["); i > -1 {
+		rest = ve.Path[i:]
+	}
+	return &ValidationError{Path: path + rest, Msg: ve.Msg}
+}
+
+// ValidationErrorAtIndex is like ValidationErrorAt, for an error in element i
+// of the array at path
+func ValidationErrorAtIndex(path string, i int, err error) error {
+	return ValidationErrorAt(fmt.Sprintf("%s[%d]", path, i), err)
+}
@@ -0,0 +1,180 @@
+package barrister
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestValidateConformIdl(t *testing.T) {
+	Equals(t, parseTestIdl().Validate(), nil)
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	src := `
+enum Status { ok err ok }
+
+enum Empty { }
+
+enum Kind { a }
+
+struct Base extends Kind {
+    status Status
+}
+
+struct Parent {
+    id string
+}
+
+struct Child extends Parent {
+    id    int
+    name  string
+    name  string
+    owner User
+}
+
+struct A extends B {
+    a string
+}
+
+struct B extends A {
+    b string
+}
+
+struct Status {
+    x string
+}
+
+interface Svc {
+    get(id string, id int, user User) Child
+    get() Missing
+}
+`
+	elems, err := ParseIdlElems("invalid.idl", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewValidatedIdl(elems)
+	errs, ok := err.(IdlErrors)
+	if !ok {
+		t.Fatalf("Expected IdlErrors, got: %v", err)
+	}
+
+	expected := []string{
+		"barrister: enum Status value ok: duplicate value",
+		"barrister: enum Empty: enum has no values",
+		"barrister: struct Base: extends enum 'Kind', only structs may be extended",
+		"barrister: struct Child field id: shadows field inherited from Parent",
+		"barrister: struct Child field name: duplicate field name",
+		"barrister: struct Child field owner: unknown type 'User'",
+		"barrister: struct A: cyclic inheritance: A -> B -> A",
+		"barrister: struct B: cyclic inheritance: B -> A -> B",
+		"barrister: struct Status: duplicate name, already declared as enum",
+		"barrister: interface Svc function get param id: duplicate param name",
+		"barrister: interface Svc function get param user: unknown type 'User'",
+		"barrister: interface Svc function get: duplicate function name",
+		"barrister: interface Svc function get returns: unknown type 'Missing'",
+	}
+	actual := make([]string, len(errs))
+	for i, e := range errs {
+		actual[i] = e.Error()
+	}
+	DeepEquals(t, actual, expected)
+
+	Equals(t, errs[10].Function, "get")
+	Equals(t, errs[10].Field, "user")
+}
+
+func TestParseIdlJsonValidates(t *testing.T) {
+	json := `[{"type": "struct", "name": "A", "extends": "Missing", "fields": []}]`
+	_, err := ParseIdlJson([]byte(json))
+	Equals(t, err.Error(), "barrister: struct A: extends unknown struct 'Missing'")
+}
+
+func TestValidationErrorAt(t *testing.T) {
+	err := ValidationErrorAt("User.home", &ValidationError{Path: "Address.country", Msg: "bad"})
+	Equals(t, err.Error(), "barrister: User.home.country: bad")
+
+	err = ValidationErrorAtIndex("User.tags", 2, &ValidationError{Path: "Tag", Msg: "bad"})
+	Equals(t, err.Error(), "barrister: User.tags[2]: bad")
+
+	err = ValidationErrorAt("User", &ValidationError{Path: "Base.ids[0]", Msg: "bad"})
+	Equals(t, err.(*ValidationError).Path, "User.ids[0]")
+}
+
+func TestServerValidateResults(t *testing.T) {
+	svr := NewJSONServer(parseTestIdl(), true)
+	svr.AddHandler("A", AImpl{})
+	svr.AddDispatcher("B", BImpl{}, bDispatcher{})
+
+	// AImpl.Repeat returns an empty status and nil items
+	req := `{"jsonrpc":"2.0","id":"1","method":"A.repeat","params":[{"to_repeat":"a","count":1,"force_uppercase":false}]}`
+	Equals(t, string(svr.InvokeBytes(newHeaders(), []byte(req))),
+		`{"jsonrpc":"2.0","id":"1","result":{"status":"","count":0,"items":null}}`)
+
+	svr.SetValidateResults(true)
+	Equals(t, string(svr.InvokeBytes(newHeaders(), []byte(req))),
+		`{"jsonrpc":"2.0","id":"1","error":{"code":-32603,"message":"barrister: A.repeat: invalid result.status: Value '' not in enum values: 'ok', 'err'"}}`)
+
+	for _, req := range []string{
+		`{"jsonrpc":"2.0","id":"1","method":"A.add","params":[1,2]}`,
+		`{"jsonrpc":"2.0","id":"1","method":"B.echo","params":["return-null"]}`,
+	} {
+		resp := JsonRpcResponse{}
+		json.Unmarshal(svr.InvokeBytes(newHeaders(), []byte(req)), &resp)
+		Equals(t, resp.Error, (*JsonRpcError)(nil))
+	}
+}
+
+// checkedHi is a HiResponse with a Validate method, which fails unless valid
+// is set
+type checkedHi struct {
+	Hi    string `json:"hi"`
+	valid bool
+}
+
+func (h checkedHi) Validate() error {
+	if h.valid {
+		return nil
+	}
+	return &ValidationError{Path: "HiResponse.hi", Msg: "not checked"}
+}
+
+// hiDispatcher returns its result from A.say_hi
+type hiDispatcher struct {
+	result interface{}
+}
+
+func (d hiDispatcher) Decode(_idl *Idl, _method string, _params []interface{}) (DispatchFunc, error) {
+	return func(_ctx context.Context, _handler interface{}) (interface{}, error) {
+		return d.result, nil
+	}, nil
+}
+
+func TestServerValidateResultsWithValidator(t *testing.T) {
+	req := `{"jsonrpc":"2.0","id":"1","method":"A.say_hi","params":[]}`
+	for _, c := range []struct {
+		result interface{}
+		resp   string
+	}{
+		{checkedHi{Hi: "hi"}, `{"jsonrpc":"2.0","id":"1","error":{"code":-32603,"message":"barrister: A.say_hi: invalid result.hi: not checked"}}`},
+		{checkedHi{Hi: "hi", valid: true}, `{"jsonrpc":"2.0","id":"1","result":{"hi":"hi"}}`},
+		{&checkedHi{Hi: "hi", valid: true}, `{"jsonrpc":"2.0","id":"1","result":{"hi":"hi"}}`},
+	} {
+		svr := NewJSONServer(parseTestIdl(), true)
+		svr.AddDispatcher("A", AImpl{}, hiDispatcher{c.result})
+		svr.SetValidateResults(true)
+		Equals(t, string(svr.InvokeBytes(newHeaders(), []byte(req))), c.resp)
+	}
+
+	// nil pointers are checked against the IDL instead
+	svr := NewJSONServer(parseTestIdl(), true)
+	svr.AddDispatcher("A", AImpl{}, hiDispatcher{(*checkedHi)(nil)})
+	svr.SetValidateResults(true)
+	resp := JsonRpcResponse{}
+	json.Unmarshal(svr.InvokeBytes(newHeaders(), []byte(req)), &resp)
+	Equals(t, resp.Error.Code, -32603)
+}